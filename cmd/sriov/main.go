@@ -1,22 +1,35 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 
+	"github.com/containernetworking/cni/pkg/invoke"
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/cni/pkg/version"
+	"github.com/containernetworking/plugins/pkg/ip"
 	"github.com/containernetworking/plugins/pkg/ipam"
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/config"
+	srioverrors "github.com/k8snetworkplumbingwg/sriov-cni/pkg/errors"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/sriov"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
-	"github.com/vishvananda/netlink"
 )
 
+// newSriovManager returns the Manager the VFs are configured through, replaced by a fake in tests
+var newSriovManager = sriov.NewSriovManager
+
 type envArgs struct {
 	types.CommonArgs
 	MAC types.UnmarshallableString `json:"mac,omitempty"`
@@ -41,15 +54,52 @@ func getEnvArgs(envArgsString string) (*envArgs, error) {
 	return nil, nil
 }
 
-func cmdAdd(args *skel.CmdArgs) error {
+// initLogging configures logging from the logLevel and logFile fields of the stdin netconf
+func initLogging(args *skel.CmdArgs) {
+	n := &sriovtypes.NetConf{}
+	// a malformed netconf is reported by the command itself
+	_ = json.Unmarshal(args.StdinData, n)
+	logging.Init(n.LogLevel, n.LogFile, args.ContainerID, args.Netns, args.IfName)
+}
+
+// withLogging sets up logging for a plugin invocation and logs the outcome of cmd
+func withLogging(cmdName string, cmd func(*skel.CmdArgs) error) func(*skel.CmdArgs) error {
+	return func(args *skel.CmdArgs) error {
+		initLogging(args)
+		logging.Info(cmdName+" called", "args", args.Args)
+
+		err := cmd(args)
+		if err != nil {
+			// skel only reports the code of a *types.Error returned as is
+			cniErr := srioverrors.ToCNI(err)
+			logging.Error(cmdName+" failed", "code", cniErr.Code, "msg", cniErr.Msg, "details", cniErr.Details)
+			return cniErr
+		}
+
+		logging.Info(cmdName + " succeeded")
+		return nil
+	}
+}
+
+func cmdAdd(args *skel.CmdArgs) (err error) {
+	// Runtimes may retry an ADD that timed out, return the result of the attachment if it is already set up
+	cachedResult, err := loadCachedResult(args)
+	if err != nil {
+		return err
+	}
+	if cachedResult != nil {
+		logging.Info("Attachment already set up, returning the cached result")
+		return cachedResult.Print()
+	}
+
 	netConf, err := config.LoadConf(args.StdinData)
 	if err != nil {
-		return fmt.Errorf("SRIOV-CNI failed to load netconf: %v", err)
+		return fmt.Errorf("SRIOV-CNI failed to load netconf: %w", err)
 	}
 
 	envArgs, err := getEnvArgs(args.Args)
 	if err != nil {
-		return fmt.Errorf("SRIOV-CNI failed to parse args: %v", err)
+		return srioverrors.Decoding(err, "SRIOV-CNI failed to parse args")
 	}
 
 	if envArgs != nil {
@@ -72,27 +122,44 @@ func cmdAdd(args *skel.CmdArgs) error {
 	}
 	defer netns.Close()
 
-	sm := sriov.NewSriovManager()
-	err = sm.FillOriginalVfInfo(netConf)
-	if err != nil {
-		return fmt.Errorf("failed to get original vf information: %v", err)
-	}
+	// Every step that succeeds is recorded in the journal. On any failure, including a failure to
+	// cache the netconf, exactly those steps are undone in reverse order.
+	// err is the named result so that no return path can bypass the rollback.
+	journal := sriov.NewJournal()
 	defer func() {
 		if err != nil {
-			err := netns.Do(func(_ ns.NetNS) error {
-				_, err := netlink.LinkByName(args.IfName)
-				return err
-			})
-			if err == nil {
-				_ = sm.ReleaseVF(netConf, args.IfName, netns)
+			logging.Info("Rolling back attachment", "steps", strings.Join(journal.Steps(), ","))
+			if rollbackErr := journal.Rollback(); rollbackErr != nil {
+				logging.Error("Failed to roll back attachment", "error", rollbackErr)
 			}
-			// Reset the VF if failure occurs before the netconf is cached
-			_ = sm.ResetVFConfig(netConf)
 		}
 	}()
-	if err := sm.ApplyVFConfig(netConf); err != nil {
-		return fmt.Errorf("SRIOV-CNI failed to configure VF %q", err)
+
+	// Claim the pci address before touching the VF so that concurrent ADDs for the same device
+	// can't both reconfigure it; the claim is released if the ADD fails
+	allocator := utils.NewPCIAllocator(config.DefaultCNIDir)
+	owner := utils.NewPCIOwner(args.ContainerID, args.IfName, args.Netns)
+	if err = allocator.ClaimPCI(netConf.DeviceID, owner); err != nil {
+		return fmt.Errorf("error claiming the pci allocation for vf pci address %s: %w", netConf.DeviceID, err)
+	}
+	journal.Record("pci allocation", func() error {
+		return allocator.DeleteAllocatedPCI(netConf.DeviceID, owner)
+	})
+
+	sm := newSriovManager()
+	if err = sm.DetectSwitchdev(netConf); err != nil {
+		return fmt.Errorf("failed to detect the eswitch mode of the PF: %w", err)
+	}
+
+	err = sm.FillOriginalVfInfo(netConf)
+	if err != nil {
+		return fmt.Errorf("failed to get original vf information: %w", err)
+	}
+
+	if err = sm.ApplyVFConfig(netConf, journal); err != nil {
+		return fmt.Errorf("SRIOV-CNI failed to configure VF: %w", err)
 	}
+	logging.Info("VF configured", "pf", netConf.Master, "vfID", netConf.VFID, "pciAddr", netConf.DeviceID, "dpdkMode", netConf.DPDKMode)
 
 	result := &current.Result{}
 	result.Interfaces = []*current.Interface{{
@@ -101,14 +168,42 @@ func cmdAdd(args *skel.CmdArgs) error {
 	}}
 
 	if !netConf.DPDKMode {
-		err = sm.SetupVF(netConf, args.IfName, netns)
+		err = sm.SetupVF(netConf, args.IfName, netns, journal)
 
 		if err != nil {
-			return fmt.Errorf("failed to set up pod interface %q from the device %q: %v", args.IfName, netConf.Master, err)
+			return fmt.Errorf("failed to set up pod interface %q from the device %q: %w", args.IfName, netConf.Master, err)
 		}
 	}
 
 	result.Interfaces[0].Mac = config.GetMacAddressForResult(netConf)
+	result.Interfaces[0].PciID = netConf.DeviceID
+	// The workload of a vhost_vdpa device opens its vhost-vdpa character device itself
+	if netConf.VdpaDevice != nil && netConf.VdpaDevice.Driver == sriovtypes.VdpaDriverVhost {
+		result.Interfaces[0].SocketPath = netConf.VdpaDevice.Path
+	}
+
+	// In switchdev mode the VF traffic goes through its representor, which is left in the host netns to be
+	// plugged into a virtual switch
+	if netConf.Representor != "" {
+		result.Interfaces = append(result.Interfaces, &current.Interface{Name: netConf.Representor})
+	}
+
+	// The vDPA device of the VF is also described in the device-info file of the attachment, for consumers
+	// that read the device-info rather than the CNI result
+	if netConf.DevInfoFile != "" {
+		var devInfo *sriovtypes.DeviceInfo
+		if devInfo, err = config.GetVdpaDeviceInfo(netConf); err != nil {
+			return fmt.Errorf("failed to get the device-info of vf %s: %v", netConf.DeviceID, err)
+		}
+		if devInfo != nil {
+			if err = utils.SaveDeviceInfo(netConf.DevInfoFile, devInfo); err != nil {
+				return err
+			}
+			journal.Record("device info", func() error {
+				return utils.CleanDeviceInfo(netConf.DevInfoFile)
+			})
+		}
+	}
 
 	// run the IPAM plugin
 	if netConf.IPAM.Type != "" {
@@ -117,12 +212,10 @@ func cmdAdd(args *skel.CmdArgs) error {
 		if err != nil {
 			return fmt.Errorf("failed to set up IPAM plugin type %q from the device %q: %v", netConf.IPAM.Type, netConf.Master, err)
 		}
-
-		defer func() {
-			if err != nil {
-				_ = ipam.ExecDel(netConf.IPAM.Type, args.StdinData)
-			}
-		}()
+		logging.Debug("IPAM plugin returned", "ipamType", netConf.IPAM.Type, "result", r)
+		journal.Record("ipam", func() error {
+			return ipam.ExecDel(netConf.IPAM.Type, args.StdinData)
+		})
 
 		// Convert the IPAM result into the current Result type
 		var newResult *current.Result
@@ -174,96 +267,553 @@ func cmdAdd(args *skel.CmdArgs) error {
 		result = newResult
 	}
 
-	// Cache NetConf for CmdDel
-	if err = utils.SaveNetConf(args.ContainerID, config.DefaultCNIDir, args.IfName, netConf); err != nil {
+	// Cache NetConf for CmdDel, along with the result for repeated ADDs and the owner of the VF for GC
+	netConf.Owner = owner
+	result.CNIVersion = current.ImplementedSpecVersion
+	if netConf.CachedResult, err = json.Marshal(result); err != nil {
+		return fmt.Errorf("error serializing result %q", err)
+	}
+	if err = config.SaveConfToCache(args.ContainerID, args.IfName, netConf); err != nil {
 		return fmt.Errorf("error saving NetConf %q", err)
 	}
+	journal.Record("netconf cache", func() error {
+		return utils.CleanCachedNetConf(config.CachePath(netConf.Name, args.ContainerID, args.IfName))
+	})
+
+	return types.PrintResult(result, netConf.CNIVersion)
+}
+
+// loadCachedResult returns the result of an earlier ADD of the attachment by the same owner, converted to
+// the CNI version of the request, after verifying that the attachment is still intact. It returns nil if
+// the attachment isn't set up yet.
+func loadCachedResult(args *skel.CmdArgs) (types.Result, error) {
+	netConf, _, err := config.LoadConfFromCache(args)
+	if err != nil || netConf.CachedResult == nil {
+		return nil, nil
+	}
 
 	allocator := utils.NewPCIAllocator(config.DefaultCNIDir)
-	// Mark the pci address as in used
-	if err = allocator.SaveAllocatedPCI(netConf.DeviceID, args.Netns); err != nil {
-		return fmt.Errorf("error saving the pci allocation for vf pci address %s: %v", netConf.DeviceID, err)
+	isOwner, err := allocator.IsOwner(netConf.DeviceID, utils.NewPCIOwner(args.ContainerID, args.IfName, args.Netns))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the pci allocation for vf pci address %s: %v", netConf.DeviceID, err)
+	}
+	if !isOwner {
+		return nil, nil
 	}
 
-	return types.PrintResult(result, netConf.CNIVersion)
+	result := &current.Result{}
+	if err = json.Unmarshal(netConf.CachedResult, result); err != nil {
+		return nil, fmt.Errorf("failed to parse cached result: %v", err)
+	}
+
+	if err = checkAttachment(newSriovManager(), netConf, result, args); err != nil {
+		return nil, fmt.Errorf("attachment is already set up but no longer intact, it must be deleted first: %v", err)
+	}
+
+	stdinConf := &types.NetConf{}
+	if err = json.Unmarshal(args.StdinData, stdinConf); err != nil {
+		return nil, fmt.Errorf("failed to load netconf: %v", err)
+	}
+	return result.GetAsVersion(stdinConf.CNIVersion)
 }
 
 func cmdDel(args *skel.CmdArgs) error {
 	netConf, cRefPath, err := config.LoadConfFromCache(args)
 	if err != nil {
-		// If cmdDel() fails, cached netconf is cleaned up by
-		// the followed defer call. However, subsequence calls
-		// of cmdDel() from kubelet fail in a dead loop due to
-		// cached netconf doesn't exist.
-		// Return nil when LoadConfFromCache fails since the rest
-		// of cmdDel() code relies on netconf as input argument
-		// and there is no meaning to continue.
-		return nil
-	}
+		// If cmdDel() fails, cached netconf is kept for the
+		// retry. Once it is removed, subsequence calls of
+		// cmdDel() from kubelet would fail in a dead loop due
+		// to cached netconf doesn't exist.
+		// Return nil when LoadConfFromCache fails and release
+		// what can be found from the stdin netconf and the pci
+		// allocation on a best-effort basis instead.
+		if errors.Is(err, config.ErrCorruptCache) {
+			logging.Error("Cached netconf is corrupt, releasing the VF without it", "path", cRefPath, "error", err)
+		} else {
+			logging.Warning("Cached netconf not found, releasing the VF without it", "error", err)
+		}
 
-	defer func() {
-		if err == nil && cRefPath != "" {
+		if err := delWithoutCache(args); err != nil {
+			logging.Error("Failed to release the VF without cached netconf", "error", err)
+			return nil
+		}
+		if cRefPath != "" {
 			_ = utils.CleanCachedNetConf(cRefPath)
 		}
-	}()
+		return nil
+	}
 
-	if netConf.IPAM.Type != "" {
-		err = ipam.ExecDel(netConf.IPAM.Type, args.StdinData)
-		if err != nil {
+	sm := newSriovManager()
+	allocator := utils.NewPCIAllocator(config.DefaultCNIDir)
+	owner := utils.NewPCIOwner(args.ContainerID, args.IfName, args.Netns)
+
+	// A late DEL, run after a new pod claimed the VF of a netns that is gone, must leave the VF alone
+	isOwner, err := ownsVF(allocator, netConf.DeviceID, owner)
+	if err != nil {
+		return fmt.Errorf("failed to check the pci allocation of vf %s: %v", netConf.DeviceID, err)
+	}
+
+	steps := []delStep{
+		{name: "ipam release", retryable: true, run: func() error {
+			if netConf.IPAM.Type == "" {
+				return nil
+			}
+			return ipam.ExecDel(netConf.IPAM.Type, args.StdinData)
+		}},
+		{name: "device info cleanup", retryable: true, run: func() error {
+			if netConf.DevInfoFile == "" || netConf.VdpaDevice == nil {
+				return nil
+			}
+			return utils.CleanDeviceInfo(netConf.DevInfoFile)
+		}},
+	}
+	if !isOwner {
+		logging.Warning("VF is allocated to another attachment, leaving it in place", "pciAddr", netConf.DeviceID)
+	} else {
+		steps = append(steps, vfDelSteps(sm, allocator, owner, netConf, args)...)
+	}
+
+	if err = runDelSteps(netConf, steps); err != nil {
+		// Keep the cached netconf for the retry
+		return err
+	}
+
+	if cRefPath != "" {
+		_ = utils.CleanCachedNetConf(cRefPath)
+	}
+	return nil
+}
+
+// ownsVF returns whether an attachment may reset and release its VF: the VF is allocated to the attachment,
+// or to no attachment at all, as after an earlier attempt released it. A VF claimed by another attachment
+// since the netns of this one is gone is left alone.
+func ownsVF(allocator *utils.PCIAllocator, pciAddr string, owner *sriovtypes.PCIOwner) (bool, error) {
+	isOwner, err := allocator.IsOwner(pciAddr, owner)
+	if err != nil || isOwner {
+		return isOwner, err
+	}
+
+	isAllocated, err := allocator.IsAllocated(pciAddr)
+	return !isAllocated, err
+}
+
+// vfDelSteps returns the teardown steps that reset and release the VF of an attachment owning it
+func vfDelSteps(sm sriov.Manager, allocator *utils.PCIAllocator, owner *sriovtypes.PCIOwner,
+	netConf *sriovtypes.NetConf, args *skel.CmdArgs) []delStep {
+	return []delStep{
+		/* ResetVFConfig resets a VF administratively. We must run ResetVFConfig
+		   before ReleaseVF because some drivers will error out if we try to
+		   reset netdev VF with trust off. So, reset VF MAC address via PF first.
+		*/
+		{name: "VF reset", retryable: true, run: func() error {
+			return sm.ResetVFConfig(netConf)
+		}},
+		// ReleaseVF renames and moves the netdev in several steps, a retry after a partial run can't find it
+		// by its pod name anymore
+		{name: "VF release", retryable: false, run: func() error {
+			if netConf.DPDKMode {
+				return nil
+			}
+			return releaseVF(sm, netConf, args)
+		}},
+		// Mark the pci address as released, unless another attachment has claimed it since. A VF that isn't
+		// reset and back in the init netns stays allocated, so that no new pod gets it in that state.
+		{name: "pci allocation release", retryable: true, after: []string{"VF reset", "VF release"}, run: func() error {
+			err := allocator.DeleteAllocatedPCI(netConf.DeviceID, owner)
+			if errors.Is(err, os.ErrNotExist) {
+				// Already released by an earlier attempt
+				return nil
+			}
+			if errors.Is(err, utils.ErrNotOwner) {
+				logging.Warning("PCI allocation belongs to another attachment, leaving it in place", "pciAddr", netConf.DeviceID, "error", err)
+				return nil
+			}
 			return err
+		}},
+	}
+}
+
+// delStep is a step of the teardown of an attachment
+type delStep struct {
+	name string
+	// retryable tells whether the step can safely be run again by a retried DEL, as opposed to a step
+	// whose failure leaves a state that needs an operator
+	retryable bool
+	// after names the earlier steps that must succeed for this one to run, it is left to the retried DEL
+	// otherwise
+	after []string
+	run   func() error
+}
+
+// runDelSteps runs the teardown steps in order, attempting every step even if an earlier one fails, except
+// the steps that depend on a failed one. The failures are combined in a single CNI error, ErrTryAgainLater
+// if all the failed steps can be retried and ErrInternal otherwise.
+func runDelSteps(netConf *sriovtypes.NetConf, steps []delStep) error {
+	var failures []string
+	failed := map[string]bool{}
+	retryable := true
+	for _, step := range steps {
+		if blocker := failedStep(step.after, failed); blocker != "" {
+			logging.Warning("Teardown step skipped", "step", step.name, "pciAddr", netConf.DeviceID, "failedStep", blocker)
+			failures = append(failures, fmt.Sprintf("%s: skipped as %s failed", step.name, blocker))
+			failed[step.name] = true
+			continue
+		}
+		if err := step.run(); err != nil {
+			logging.Error("Teardown step failed", "step", step.name, "pciAddr", netConf.DeviceID, "retryable", step.retryable, "error", err)
+			failures = append(failures, fmt.Sprintf("%s: %v", step.name, err))
+			failed[step.name] = true
+			retryable = retryable && step.retryable
 		}
 	}
 
-	// https://github.com/kubernetes/kubernetes/pull/35240
-	if args.Netns == "" {
+	if len(failures) == 0 {
 		return nil
 	}
+	if retryable {
+		return types.NewError(types.ErrTryAgainLater,
+			fmt.Sprintf("failed to release VF %s, retrying may succeed", netConf.DeviceID), strings.Join(failures, "; "))
+	}
+	return types.NewError(types.ErrInternal,
+		fmt.Sprintf("failed to release VF %s, operator intervention is needed", netConf.DeviceID), strings.Join(failures, "; "))
+}
+
+// failedStep returns the first of names found in failed, or an empty string if none is
+func failedStep(names []string, failed map[string]bool) string {
+	for _, name := range names {
+		if failed[name] {
+			return name
+		}
+	}
+	return ""
+}
 
-	sm := sriov.NewSriovManager()
+// releaseVF returns the VF netdev from the Pod netns to the init netns
+func releaseVF(sm sriov.Manager, netConf *sriovtypes.NetConf, args *skel.CmdArgs) error {
+	// The netns is empty when the sandbox is already gone, see
+	// https://github.com/kubernetes/kubernetes/pull/35240
+	if args.Netns != "" {
+		netns, err := ns.GetNS(args.Netns)
+		if err == nil {
+			defer netns.Close()
+			return sm.ReleaseVF(netConf, args.IfName, netns)
+		}
 
-	/* ResetVFConfig resets a VF administratively. We must run ResetVFConfig
-	   before ReleaseVF because some drivers will error out if we try to
-	   reset netdev VF with trust off. So, reset VF MAC address via PF first.
-	*/
-	if err := sm.ResetVFConfig(netConf); err != nil {
-		return fmt.Errorf("cmdDel() error reseting VF: %q", err)
+		// according to:
+		// https://github.com/kubernetes/kubernetes/issues/43014#issuecomment-287164444
+		// if provided path does not exist (e.x. when node was restarted)
+		// plugin should silently return with success after releasing
+		// IPAM resources
+		if _, ok := err.(ns.NSPathNotExistErr); !ok {
+			return fmt.Errorf("failed to open netns %s: %q", args.Netns, err)
+		}
+		logging.Warning("Pod netns does not exist anymore", "error", err)
 	}
 
-	if !netConf.DPDKMode {
+	// Without the netns the kernel returns the VF netdev to the init netns by itself
+	return sm.RestoreVFHostState(netConf)
+}
+
+// delWithoutCache releases the VF of an attachment whose cached NetConf is missing or corrupt, relying on the
+// stdin netconf and the pci allocation. The IPAM address is always released, while the VF is only touched if
+// it is still allocated to the attachment. Every step is attempted even if an earlier one fails, and all the
+// failures are returned.
+func delWithoutCache(args *skel.CmdArgs) error {
+	stdinConf := &types.NetConf{}
+	if err := json.Unmarshal(args.StdinData, stdinConf); err != nil {
+		return fmt.Errorf("failed to load netconf: %v", err)
+	}
+
+	var errs []error
+	if stdinConf.IPAM.Type != "" {
+		if err := ipam.ExecDel(stdinConf.IPAM.Type, args.StdinData); err != nil {
+			errs = append(errs, fmt.Errorf("failed to release IPAM: %v", err))
+		}
+	}
+
+	allocator := utils.NewPCIAllocator(config.DefaultCNIDir)
+	owner := utils.NewPCIOwner(args.ContainerID, args.IfName, args.Netns)
+
+	deviceID, err := allocator.FindAllocatedPCI(owner)
+	if err != nil {
+		logging.Warning("Failed to look up the pci allocation of the attachment", "error", err)
+	}
+
+	netConf, err := config.LoadConfWithoutCache(args.StdinData, deviceID)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	isOwner, err := allocator.IsOwner(netConf.DeviceID, owner)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	if !isOwner {
+		logging.Warning("VF is not allocated to the attachment, nothing to release", "pciAddr", netConf.DeviceID)
+		return errors.Join(errs...)
+	}
+
+	sm := newSriovManager()
+	if err = sm.DetectSwitchdev(netConf); err != nil {
+		errs = append(errs, err)
+	}
+	if err = sm.ResetVFToDefault(netConf); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reset VF: %v", err))
+	}
+
+	if !netConf.DPDKMode && args.Netns != "" {
 		netns, err := ns.GetNS(args.Netns)
-		if err != nil {
-			// according to:
-			// https://github.com/kubernetes/kubernetes/issues/43014#issuecomment-287164444
-			// if provided path does not exist (e.x. when node was restarted)
-			// plugin should silently return with success after releasing
-			// IPAM resources
-			_, ok := err.(ns.NSPathNotExistErr)
-			if ok {
-				return nil
+		if err == nil {
+			if err = sm.ReleaseVFByPCI(netConf, netns); err != nil {
+				errs = append(errs, err)
 			}
+			netns.Close()
+		} else if _, ok := err.(ns.NSPathNotExistErr); !ok {
+			errs = append(errs, fmt.Errorf("failed to open netns %s: %v", args.Netns, err))
+		}
+	}
 
-			return fmt.Errorf("failed to open netns %s: %q", netns, err)
+	if err = allocator.DeleteAllocatedPCI(netConf.DeviceID, owner); err != nil {
+		errs = append(errs, fmt.Errorf("error cleaning the pci allocation for vf pci address %s: %v", netConf.DeviceID, err))
+	}
+
+	if netConf.DevInfoFile != "" && netConf.VdpaDevice != nil {
+		if err = utils.CleanDeviceInfo(netConf.DevInfoFile); err != nil {
+			errs = append(errs, err)
 		}
-		defer netns.Close()
+	}
 
-		if err = sm.ReleaseVF(netConf, args.IfName, netns); err != nil {
-			return err
+	if len(errs) == 0 {
+		logging.Info("Released VF without cached netconf", "pf", netConf.Master, "vfID", netConf.VFID, "pciAddr", netConf.DeviceID)
+	}
+	return errors.Join(errs...)
+}
+
+func cmdCheck(args *skel.CmdArgs) error {
+	netConf, _, err := config.LoadConfFromCache(args)
+	if err != nil {
+		return types.NewError(types.ErrUnknownContainer, "failed to load cached netconf", err.Error())
+	}
+
+	prevResult, err := loadPrevResult(args.StdinData)
+	if err != nil {
+		return types.NewError(types.ErrDecodingFailure, "failed to parse prevResult", err.Error())
+	}
+
+	return checkAttachment(newSriovManager(), netConf, prevResult, args)
+}
+
+// checkAttachment verifies that the VF configuration and the pod interface of an attachment still match
+// its netconf and the result of its ADD
+func checkAttachment(sm sriov.Manager, netConf *sriovtypes.NetConf, result *current.Result, args *skel.CmdArgs) error {
+	var contIntf *current.Interface
+	for _, intf := range result.Interfaces {
+		if intf.Name == args.IfName && intf.Sandbox == args.Netns {
+			contIntf = intf
+			break
 		}
 	}
 
-	// Mark the pci address as released
+	// A DPDK VF has no pod interface to compare the MAC address with, its administrative MAC address is
+	// checked against the one returned by ADD instead
+	if netConf.DPDKMode && netConf.MAC == "" && contIntf != nil && contIntf.Mac != "" {
+		expected := *netConf
+		expected.MAC = contIntf.Mac
+		netConf = &expected
+	}
+
+	if err := sm.CheckVFConfig(netConf); err != nil {
+		return types.NewError(types.ErrInternal, "VF configuration check failed", err.Error())
+	}
+
+	// Nothing is moved into the pod netns in DPDK mode
+	if netConf.DPDKMode {
+		return nil
+	}
+
+	if contIntf == nil {
+		return types.NewError(types.ErrInternal, "pod interface check failed",
+			fmt.Sprintf("interface %s in netns %s not found in result", args.IfName, args.Netns))
+	}
+
+	netns, err := ns.GetNS(args.Netns)
+	if err != nil {
+		return types.NewError(types.ErrInternal, "pod interface check failed",
+			fmt.Sprintf("failed to open netns %q: %v", args.Netns, err))
+	}
+	defer netns.Close()
+
+	if err = sm.CheckVF(args.IfName, contIntf.Mac, netns); err != nil {
+		return types.NewError(types.ErrInternal, "pod interface check failed", err.Error())
+	}
+
+	err = netns.Do(func(_ ns.NetNS) error {
+		return ip.ValidateExpectedInterfaceIPs(args.IfName, result.IPs)
+	})
+	if err != nil {
+		return types.NewError(types.ErrInternal, "pod interface IP check failed", err.Error())
+	}
+
+	return nil
+}
+
+// loadPrevResult parses the prevResult passed to CHECK into the current Result type
+func loadPrevResult(stdinData []byte) (*current.Result, error) {
+	conf := &types.NetConf{}
+	if err := json.Unmarshal(stdinData, conf); err != nil {
+		return nil, fmt.Errorf("failed to load netconf: %v", err)
+	}
+
+	if err := version.ParsePrevResult(conf); err != nil {
+		return nil, err
+	}
+
+	if conf.PrevResult == nil {
+		return nil, fmt.Errorf("required prevResult missing")
+	}
+
+	return current.NewResultFromResult(conf.PrevResult)
+}
+
+func cmdGC(args *skel.CmdArgs) error {
+	gcConf, err := config.LoadGCConf(args.StdinData)
+	if err != nil {
+		return types.NewError(types.ErrDecodingFailure, "failed to load netconf", err.Error())
+	}
+
+	validAttachments := make(map[string]bool, len(gcConf.ValidAttachments))
+	for _, attachment := range gcConf.ValidAttachments {
+		validAttachments[config.CacheRef(attachment.ContainerID, attachment.IfName)] = true
+	}
+
+	cachedConfs, err := config.LoadAllConfsFromCache(gcConf.Name)
+	if err != nil {
+		return types.NewError(types.ErrIOFailure, "failed to list cached netconfs", err.Error())
+	}
+
+	sm := newSriovManager()
 	allocator := utils.NewPCIAllocator(config.DefaultCNIDir)
-	if err = allocator.DeleteAllocatedPCI(netConf.DeviceID); err != nil {
-		return fmt.Errorf("error cleaning the pci allocation for vf pci address %s: %v", netConf.DeviceID, err)
+
+	var failures []string
+	for cRefPath, netConf := range cachedConfs {
+		cRef := filepath.Base(cRefPath)
+		if validAttachments[cRef] {
+			continue
+		}
+
+		if err := gcAttachment(sm, allocator, cRefPath, netConf, args.StdinData); err != nil {
+			logging.Error("Failed to clean up stale attachment", "attachment", cRef, "pciAddr", netConf.DeviceID, "error", err)
+			failures = append(failures, fmt.Sprintf("%s (vf %s): %v", cRef, netConf.DeviceID, err))
+			continue
+		}
+		logging.Info("Cleaned up stale attachment", "attachment", cRef, "pf", netConf.Master, "vfID", netConf.VFID, "pciAddr", netConf.DeviceID)
+	}
+
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return types.NewError(types.ErrTryAgainLater,
+			fmt.Sprintf("failed to clean up %d stale attachment(s)", len(failures)), strings.Join(failures, "; "))
 	}
 
 	return nil
 }
 
-func cmdCheck(_ *skel.CmdArgs) error {
+// gcAttachment releases the IPAM addresses of a stale attachment and, if the attachment still owns its VF,
+// resets the VF and removes its allocation. A VF claimed by another attachment since is left alone. The
+// device-info file of the attachment is removed like on DEL.
+// Every step is attempted even if an earlier one fails, and the cache is only removed once all succeeded.
+func gcAttachment(sm sriov.Manager, allocator *utils.PCIAllocator, cRefPath string, netConf *sriovtypes.NetConf, stdinData []byte) error {
+	// NetConfs cached by older versions don't record their owner, only allocations of older versions match it
+	owner := netConf.Owner
+	if owner == nil {
+		owner = &sriovtypes.PCIOwner{}
+	}
+
+	var errs []error
+	if netConf.IPAM.Type != "" {
+		if owner.ContainerID == "" {
+			logging.Warning("Stale attachment has no recorded owner, not releasing its IPAM addresses", "path", cRefPath)
+		} else if err := execIPAMDel(netConf.IPAM.Type, stdinData, owner); err != nil {
+			errs = append(errs, fmt.Errorf("failed to release IPAM: %v", err))
+		}
+	}
+
+	isOwner, err := ownsVF(allocator, netConf.DeviceID, owner)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to check the pci allocation: %v", err))
+	} else if isOwner {
+		// A VF that isn't reset stays allocated, so that no new pod gets it in that state
+		if err = sm.ResetVFConfig(netConf); err != nil {
+			errs = append(errs, fmt.Errorf("error resetting VF: %v", err))
+		} else if err = allocator.DeleteAllocatedPCI(netConf.DeviceID, owner); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	} else {
+		logging.Info("VF of stale attachment is allocated to another attachment, leaving it in place", "pciAddr", netConf.DeviceID)
+	}
+
+	if netConf.DevInfoFile != "" && netConf.VdpaDevice != nil {
+		if err = utils.CleanDeviceInfo(netConf.DevInfoFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return utils.CleanCachedNetConf(cRefPath)
+}
+
+// execIPAMDel runs DEL of the IPAM plugin for the attachment of owner rather than the one of the invocation
+func execIPAMDel(plugin string, netconf []byte, owner *sriovtypes.PCIOwner) error {
+	cniPath := os.Getenv("CNI_PATH")
+	pluginPath, err := invoke.FindInPath(plugin, filepath.SplitList(cniPath))
+	if err != nil {
+		return err
+	}
+
+	return invoke.ExecPluginWithoutResult(context.TODO(), pluginPath, netconf, &invoke.Args{
+		Command:     "DEL",
+		ContainerID: owner.ContainerID,
+		NetNS:       owner.NetnsPath,
+		IfName:      owner.IfName,
+		Path:        cniPath,
+	}, nil)
+}
+
+// errPluginNotAvailable is the CNI 1.1 STATUS error code reported when ADD requests can't be serviced.
+// The CNI library only defines the error codes of the 1.0 spec.
+const errPluginNotAvailable uint = 50
+
+func cmdStatus(_ *skel.CmdArgs) error {
+	if err := utils.CheckDirWritable(config.DefaultCNIDir); err != nil {
+		return types.NewError(errPluginNotAvailable, "data directory is not writable", err.Error())
+	}
+
+	for _, sysfsDir := range []string{utils.NetDirectory, utils.SysBusPci} {
+		if _, err := os.ReadDir(sysfsDir); err != nil {
+			return types.NewError(errPluginNotAvailable, "sysfs is not readable", err.Error())
+		}
+	}
+
+	pfs, err := utils.GetSriovPFs()
+	if err != nil {
+		return types.NewError(errPluginNotAvailable, "failed to list SR-IOV PFs", err.Error())
+	}
+	if len(pfs) == 0 {
+		return types.NewError(errPluginNotAvailable, "no SR-IOV PF with VFs configured found on the node",
+			fmt.Sprintf("no device under %s reports a non-zero sriov_numvfs", utils.NetDirectory))
+	}
+
 	return nil
 }
 
 func main() {
-	skel.PluginMain(cmdAdd, cmdCheck, cmdDel, version.All, "")
+	skel.PluginMainFuncs(skel.CNIFuncs{
+		Add:    withLogging("ADD", cmdAdd),
+		Check:  withLogging("CHECK", cmdCheck),
+		Del:    withLogging("DEL", cmdDel),
+		GC:     withLogging("GC", cmdGC),
+		Status: withLogging("STATUS", cmdStatus),
+	}, version.All, "")
 }
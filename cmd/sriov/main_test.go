@@ -0,0 +1,557 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containernetworking/plugins/pkg/testutils"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/config"
+	srioverrors "github.com/k8snetworkplumbingwg/sriov-cni/pkg/errors"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/sriov"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
+
+// checkedManager records the NetConf its VF configuration is checked against, failing the check with err
+type checkedManager struct {
+	sriov.Manager
+	checked *sriovtypes.NetConf
+	err     error
+}
+
+func (m *checkedManager) CheckVFConfig(conf *sriovtypes.NetConf) error {
+	m.checked = conf
+	return m.err
+}
+
+func (m *checkedManager) CheckVF(_, _ string, _ ns.NetNS) error {
+	return nil
+}
+
+// fakeManager runs the VF setup and reset steps without touching the host, finding the configured representor
+// and failing with the configured errors
+type fakeManager struct {
+	sriov.Manager
+	representor string
+	fillErr     error
+	setupErr    error
+	resetErr    error
+}
+
+func (m *fakeManager) DetectSwitchdev(conf *sriovtypes.NetConf) error {
+	conf.Representor = m.representor
+	return nil
+}
+
+func (m *fakeManager) FillOriginalVfInfo(_ *sriovtypes.NetConf) error {
+	return m.fillErr
+}
+
+func (m *fakeManager) ApplyVFConfig(_ *sriovtypes.NetConf, _ *sriov.Journal) error {
+	return nil
+}
+
+func (m *fakeManager) ResetVFConfig(_ *sriovtypes.NetConf) error {
+	return m.resetErr
+}
+
+func (m *fakeManager) SetupVF(_ *sriovtypes.NetConf, _ string, _ ns.NetNS, _ *sriov.Journal) error {
+	return m.setupErr
+}
+
+// cmdAddQuiet runs cmdAdd, discarding the result it prints to stdout
+func cmdAddQuiet(args *skel.CmdArgs) error {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer devNull.Close()
+
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() {
+		os.Stdout = stdout
+	}()
+	return cmdAdd(args)
+}
+
+var _ = Describe("Sriov CNI", func() {
+	var tmpdir string
+	var originCNIDir string
+
+	BeforeEach(func() {
+		var err error
+		tmpdir, err = os.MkdirTemp("/tmp", "sriovplugin-testfiles-")
+		Expect(err).ToNot(HaveOccurred())
+		originCNIDir = config.DefaultCNIDir
+		config.DefaultCNIDir = tmpdir
+	})
+	AfterEach(func() {
+		config.DefaultCNIDir = originCNIDir
+		os.RemoveAll(tmpdir)
+	})
+
+	Context("Checking cmdAdd function", func() {
+		var targetNetNS ns.NetNS
+		var args *skel.CmdArgs
+		var manager *fakeManager
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			args = &skel.CmdArgs{
+				ContainerID: "cid",
+				IfName:      "net1",
+				Netns:       targetNetNS.Path(),
+				StdinData:   []byte(`{"cniVersion":"1.0.0","name":"mynet","type":"sriov","deviceID":"0000:af:06.0"}`),
+			}
+			manager = &fakeManager{}
+			newSriovManager = func() sriov.Manager { return manager }
+		})
+		AfterEach(func() {
+			newSriovManager = sriov.NewSriovManager
+			targetNetNS.Close()
+			Expect(testutils.UnmountNS(targetNetNS)).To(Succeed())
+		})
+
+		It("Reports the vhost-vdpa device of a vhost_vdpa VF in the result", func() {
+			args.StdinData = []byte(`{"cniVersion":"1.1.0","name":"mynet","type":"sriov","deviceID":"0000:3b:00.3"}`)
+
+			Expect(cmdAddQuiet(args)).To(Succeed())
+
+			netConf, _, err := config.LoadConfFromCache(args)
+			Expect(err).NotTo(HaveOccurred())
+			result := &current.Result{}
+			Expect(json.Unmarshal(netConf.CachedResult, result)).To(Succeed())
+			Expect(result.Interfaces).To(HaveLen(1))
+			Expect(result.Interfaces[0].PciID).To(Equal("0000:3b:00.3"))
+			Expect(result.Interfaces[0].SocketPath).To(Equal("/dev/vhost-vdpa-1"))
+		})
+		It("Reports the SF and its representor in the result", func() {
+			args.StdinData = []byte(`{"cniVersion":"1.1.0","name":"mynet","type":"sriov","deviceID":"mlx5_core.sf.4"}`)
+			manager.representor = "enp175s0f1pf1sf4"
+
+			Expect(cmdAddQuiet(args)).To(Succeed())
+
+			netConf, _, err := config.LoadConfFromCache(args)
+			Expect(err).NotTo(HaveOccurred())
+			result := &current.Result{}
+			Expect(json.Unmarshal(netConf.CachedResult, result)).To(Succeed())
+			Expect(result.Interfaces).To(HaveLen(2))
+			Expect(result.Interfaces[0].PciID).To(Equal("mlx5_core.sf.4"))
+			Expect(result.Interfaces[1].Name).To(Equal("enp175s0f1pf1sf4"))
+			Expect(result.Interfaces[1].Sandbox).To(BeEmpty())
+		})
+		It("Reports the CNI code of a FillOriginalVfInfo error", func() {
+			manager.fillErr = srioverrors.InvalidConfig("vf 0 is in use")
+
+			err := cmdAdd(args)
+			Expect(err).To(HaveOccurred())
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+
+			isAllocated, err := utils.NewPCIAllocator(tmpdir).IsAllocated("0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isAllocated).To(BeFalse())
+		})
+		It("Reports the CNI code of a SetupVF error", func() {
+			manager.setupErr = srioverrors.InvalidConfig("requested MTU 9000 is above the MTU 1500 of the PF")
+
+			err := cmdAdd(args)
+			Expect(err).To(HaveOccurred())
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+
+			isAllocated, err := utils.NewPCIAllocator(tmpdir).IsAllocated("0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isAllocated).To(BeFalse())
+		})
+	})
+	Context("Checking loadCachedResult function", func() {
+		var targetNetNS ns.NetNS
+		var args *skel.CmdArgs
+		var owner *sriovtypes.PCIOwner
+		var cached *current.Result
+		var manager *checkedManager
+
+		BeforeEach(func() {
+			var err error
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			args = &skel.CmdArgs{
+				ContainerID: "cid",
+				IfName:      "net1",
+				Netns:       targetNetNS.Path(),
+				StdinData:   []byte(`{"cniVersion":"1.1.0","name":"mynet","type":"sriov","deviceID":"0000:af:06.0"}`),
+			}
+			owner = utils.NewPCIOwner(args.ContainerID, args.IfName, args.Netns)
+
+			cached = &current.Result{CNIVersion: current.ImplementedSpecVersion, Interfaces: []*current.Interface{
+				{Name: "net1", Sandbox: args.Netns, Mac: "d2:fc:22:a7:0d:e8", PciID: "0000:af:06.0"},
+			}}
+			netConf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0, Owner: owner}
+			netConf.Name = "mynet"
+			netConf.CachedResult, err = json.Marshal(cached)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.SaveConfToCache(args.ContainerID, args.IfName, netConf)).To(Succeed())
+
+			manager = &checkedManager{}
+			newSriovManager = func() sriov.Manager { return manager }
+		})
+		AfterEach(func() {
+			newSriovManager = sriov.NewSriovManager
+			targetNetNS.Close()
+			Expect(testutils.UnmountNS(targetNetNS)).To(Succeed())
+		})
+
+		It("Returns the cached result of an intact attachment unchanged", func() {
+			Expect(utils.NewPCIAllocator(tmpdir).ClaimPCI("0000:af:06.0", owner)).To(Succeed())
+
+			result, err := loadCachedResult(args)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(cached))
+			Expect(manager.checked).NotTo(BeNil())
+		})
+		It("Ignores a cached result when the VF is allocated to another attachment", func() {
+			otherOwner := utils.NewPCIOwner("other-cid", "net1", args.Netns)
+			Expect(utils.NewPCIAllocator(tmpdir).ClaimPCI("0000:af:06.0", otherOwner)).To(Succeed())
+
+			result, err := loadCachedResult(args)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(BeNil())
+			Expect(manager.checked).To(BeNil())
+		})
+		It("Refuses the cached result of an attachment that is no longer intact", func() {
+			Expect(utils.NewPCIAllocator(tmpdir).ClaimPCI("0000:af:06.0", owner)).To(Succeed())
+			manager.err = errors.New("vf 0 vlan 0 does not match the configured vlan 100")
+
+			result, err := loadCachedResult(args)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("it must be deleted first"))
+			Expect(result).To(BeNil())
+		})
+	})
+	Context("Checking cmdCheck function", func() {
+		It("Reports an attachment without a cached netconf as unknown", func() {
+			err := cmdCheck(&skel.CmdArgs{
+				ContainerID: "cid",
+				IfName:      "net1",
+				StdinData:   []byte(`{"cniVersion":"1.0.0","name":"mynet","type":"sriov"}`),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrUnknownContainer)))
+		})
+		It("Requires a prevResult", func() {
+			netConf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0}
+			netConf.Name = "mynet"
+			Expect(utils.SaveNetConf("cid", tmpdir, "net1", netConf)).To(Succeed())
+
+			err := cmdCheck(&skel.CmdArgs{
+				ContainerID: "cid",
+				IfName:      "net1",
+				StdinData:   []byte(`{"cniVersion":"1.0.0","name":"mynet","type":"sriov"}`),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrDecodingFailure)))
+			Expect(err.Error()).To(ContainSubstring("required prevResult missing"))
+		})
+	})
+	Context("Checking checkAttachment function", func() {
+		It("Checks the administrative MAC address of a DPDK VF against the result", func() {
+			args := &skel.CmdArgs{ContainerID: "cid", IfName: "net1", Netns: "/var/run/netns/pod"}
+			netConf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0, DPDKMode: true}
+			result := &current.Result{Interfaces: []*current.Interface{
+				{Name: "net1", Sandbox: "/var/run/netns/pod", Mac: "d2:fc:22:a7:0d:e8"},
+			}}
+
+			sm := &checkedManager{}
+			Expect(checkAttachment(sm, netConf, result, args)).To(Succeed())
+			Expect(sm.checked.MAC).To(Equal("d2:fc:22:a7:0d:e8"))
+			Expect(netConf.MAC).To(BeEmpty())
+		})
+	})
+	Context("Checking cmdDel function", func() {
+		var newNetNS ns.NetNS
+
+		AfterEach(func() {
+			if newNetNS != nil {
+				newNetNS.Close()
+				Expect(testutils.UnmountNS(newNetNS)).To(Succeed())
+				newNetNS = nil
+			}
+		})
+
+		It("Resets the VF through the PF when the pod netns is gone", func() {
+			args := &skel.CmdArgs{
+				ContainerID: "cid",
+				IfName:      "net1",
+				StdinData:   []byte(`{"name":"mynet","type":"sriov"}`),
+			}
+			netConf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0, MAC: "d2:fc:22:a7:0d:e8"}
+			netConf.Name = "mynet"
+			Expect(config.SaveConfToCache(args.ContainerID, args.IfName, netConf)).To(Succeed())
+
+			allocator := utils.NewPCIAllocator(tmpdir)
+			owner := utils.NewPCIOwner(args.ContainerID, args.IfName, args.Netns)
+			Expect(allocator.ClaimPCI(netConf.DeviceID, owner)).To(Succeed())
+
+			// the PF doesn't exist on the test host, so the VF reset is attempted and fails
+			err := cmdDel(args)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to lookup master"))
+			Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrTryAgainLater)))
+
+			// the VF isn't reset, so it's kept from a new pod until the retried DEL
+			isOwner, err := allocator.IsOwner(netConf.DeviceID, owner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isOwner).To(BeTrue())
+		})
+		It("Leaves a VF claimed by another pod alone on a late DEL", func() {
+			args := &skel.CmdArgs{
+				ContainerID: "old-cid",
+				IfName:      "net1",
+				Netns:       "/var/run/netns/does-not-exist",
+				StdinData:   []byte(`{"name":"mynet","type":"sriov"}`),
+			}
+			netConf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0, MAC: "d2:fc:22:a7:0d:e8"}
+			netConf.Name = "mynet"
+			Expect(config.SaveConfToCache(args.ContainerID, args.IfName, netConf)).To(Succeed())
+
+			// the netns of the old pod is gone, so a new pod could claim its VF
+			var err error
+			newNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			allocator := utils.NewPCIAllocator(tmpdir)
+			newOwner := utils.NewPCIOwner("new-cid", "net1", newNetNS.Path())
+			Expect(allocator.ClaimPCI(netConf.DeviceID, newOwner)).To(Succeed())
+
+			Expect(cmdDel(args)).To(Succeed())
+
+			isOwner, err := allocator.IsOwner(netConf.DeviceID, newOwner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isOwner).To(BeTrue())
+			Expect(config.CachePath("mynet", args.ContainerID, args.IfName)).NotTo(BeAnExistingFile())
+		})
+	})
+	Context("Checking delWithoutCache function", func() {
+		var newNetNS ns.NetNS
+
+		AfterEach(func() {
+			if newNetNS != nil {
+				newNetNS.Close()
+				Expect(testutils.UnmountNS(newNetNS)).To(Succeed())
+				newNetNS = nil
+			}
+		})
+
+		// the IPAM plugin isn't installed on the test host, so its release is attempted and fails
+		It("Releases the IPAM address when the VF is unknown", func() {
+			err := delWithoutCache(&skel.CmdArgs{
+				ContainerID: "cid",
+				IfName:      "net1",
+				StdinData:   []byte(`{"cniVersion":"1.0.0","name":"mynet","type":"sriov","ipam":{"type":"no-such-ipam"}}`),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to release IPAM"))
+			Expect(err.Error()).To(ContainSubstring("VF pci addr is unknown"))
+		})
+		It("Releases the IPAM address when the VF was claimed by another pod", func() {
+			var err error
+			newNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			allocator := utils.NewPCIAllocator(tmpdir)
+			newOwner := utils.NewPCIOwner("new-cid", "net1", newNetNS.Path())
+			Expect(allocator.ClaimPCI("0000:af:06.0", newOwner)).To(Succeed())
+
+			err = delWithoutCache(&skel.CmdArgs{
+				ContainerID: "old-cid",
+				IfName:      "net1",
+				Netns:       "/var/run/netns/does-not-exist",
+				StdinData:   []byte(`{"cniVersion":"1.0.0","name":"mynet","type":"sriov","deviceID":"0000:af:06.0","ipam":{"type":"no-such-ipam"}}`),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to release IPAM"))
+
+			isOwner, err := allocator.IsOwner("0000:af:06.0", newOwner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isOwner).To(BeTrue())
+		})
+	})
+	Context("Checking cmdGC function", func() {
+		var newNetNS ns.NetNS
+
+		AfterEach(func() {
+			if newNetNS != nil {
+				newNetNS.Close()
+				Expect(testutils.UnmountNS(newNetNS)).To(Succeed())
+				newNetNS = nil
+			}
+		})
+
+		It("Leaves the valid attachments alone", func() {
+			netConf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0}
+			netConf.Name = "mynet"
+			Expect(utils.SaveNetConf("cid", tmpdir, "net1", netConf)).To(Succeed())
+
+			Expect(cmdGC(&skel.CmdArgs{
+				StdinData: []byte(`{"cniVersion":"1.1.0","name":"mynet","type":"sriov",
+					"cni.dev/valid-attachments":[{"containerID":"cid","ifname":"net1"}]}`),
+			})).To(Succeed())
+			Expect(filepath.Join(tmpdir, "cid-net1")).To(BeAnExistingFile())
+		})
+		It("Drops the cache of a stale attachment whose VF was claimed by another pod", func() {
+			oldOwner := utils.NewPCIOwner("old-cid", "net1", "/var/run/netns/does-not-exist")
+			netConf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0, Owner: oldOwner}
+			netConf.Name = "mynet"
+			Expect(config.SaveConfToCache(oldOwner.ContainerID, oldOwner.IfName, netConf)).To(Succeed())
+
+			var err error
+			newNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			allocator := utils.NewPCIAllocator(tmpdir)
+			newOwner := utils.NewPCIOwner("new-cid", "net1", newNetNS.Path())
+			Expect(allocator.ClaimPCI(netConf.DeviceID, newOwner)).To(Succeed())
+
+			Expect(cmdGC(&skel.CmdArgs{
+				StdinData: []byte(`{"cniVersion":"1.1.0","name":"mynet","type":"sriov","cni.dev/valid-attachments":[]}`),
+			})).To(Succeed())
+
+			isOwner, err := allocator.IsOwner(netConf.DeviceID, newOwner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isOwner).To(BeTrue())
+			Expect(config.CachePath("mynet", oldOwner.ContainerID, oldOwner.IfName)).NotTo(BeAnExistingFile())
+		})
+		It("Keeps the cache of a stale attachment whose VF can't be reset", func() {
+			owner := utils.NewPCIOwner("old-cid", "net1", "/var/run/netns/does-not-exist")
+			netConf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0, Owner: owner}
+			netConf.Name = "mynet"
+			Expect(config.SaveConfToCache(owner.ContainerID, owner.IfName, netConf)).To(Succeed())
+			allocator := utils.NewPCIAllocator(tmpdir)
+			Expect(allocator.ClaimPCI(netConf.DeviceID, owner)).To(Succeed())
+
+			// the PF doesn't exist on the test host, so the VF reset fails
+			err := cmdGC(&skel.CmdArgs{
+				StdinData: []byte(`{"cniVersion":"1.1.0","name":"mynet","type":"sriov","cni.dev/valid-attachments":[]}`),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrTryAgainLater)))
+			Expect(err.Error()).To(ContainSubstring("failed to clean up 1 stale attachment(s)"))
+			Expect(config.CachePath("mynet", owner.ContainerID, owner.IfName)).To(BeAnExistingFile())
+
+			// the allocation of the VF is kept until it's reset
+			isOwner, err := allocator.IsOwner(netConf.DeviceID, owner)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isOwner).To(BeTrue())
+		})
+		It("Removes the device-info file of a stale attachment", func() {
+			newSriovManager = func() sriov.Manager { return &fakeManager{} }
+			defer func() {
+				newSriovManager = sriov.NewSriovManager
+			}()
+
+			owner := utils.NewPCIOwner("old-cid", "net1", "/var/run/netns/does-not-exist")
+			netConf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:3b:00.3", VFID: 1, Owner: owner,
+				VdpaDevice:  &sriovtypes.VdpaDevice{Name: "vdpa1", Driver: sriovtypes.VdpaDriverVhost, Path: "/dev/vhost-vdpa-1"},
+				DevInfoFile: filepath.Join(tmpdir, "device-info.json")}
+			netConf.Name = "mynet"
+			Expect(config.SaveConfToCache(owner.ContainerID, owner.IfName, netConf)).To(Succeed())
+			Expect(os.WriteFile(netConf.DevInfoFile, []byte(`{"type":"vdpa"}`), 0o600)).To(Succeed())
+			allocator := utils.NewPCIAllocator(tmpdir)
+			Expect(allocator.ClaimPCI(netConf.DeviceID, owner)).To(Succeed())
+
+			Expect(cmdGC(&skel.CmdArgs{
+				StdinData: []byte(`{"cniVersion":"1.1.0","name":"mynet","type":"sriov","cni.dev/valid-attachments":[]}`),
+			})).To(Succeed())
+
+			Expect(netConf.DevInfoFile).NotTo(BeAnExistingFile())
+			Expect(config.CachePath("mynet", owner.ContainerID, owner.IfName)).NotTo(BeAnExistingFile())
+			isAllocated, err := allocator.IsAllocated(netConf.DeviceID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isAllocated).To(BeFalse())
+		})
+	})
+	Context("Checking runDelSteps function", func() {
+		var netConf *sriovtypes.NetConf
+		var ran []string
+
+		// step returns a teardown step recording its run and failing with err
+		step := func(name string, retryable bool, err error, after ...string) delStep {
+			return delStep{name: name, retryable: retryable, after: after, run: func() error {
+				ran = append(ran, name)
+				return err
+			}}
+		}
+
+		BeforeEach(func() {
+			netConf = &sriovtypes.NetConf{DeviceID: "0000:af:06.0"}
+			ran = nil
+		})
+
+		It("Runs every step", func() {
+			err := runDelSteps(netConf, []delStep{
+				step("first", true, nil),
+				step("second", false, nil),
+				step("third", true, nil, "first", "second"),
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ran).To(Equal([]string{"first", "second", "third"}))
+		})
+		It("Reports the failure of retryable steps as ErrTryAgainLater", func() {
+			err := runDelSteps(netConf, []delStep{
+				step("first", true, errors.New("busy")),
+				step("second", true, nil),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrTryAgainLater)))
+			Expect(err.Error()).To(ContainSubstring("first: busy"))
+			Expect(ran).To(Equal([]string{"first", "second"}))
+		})
+		It("Reports the failure of a non-retryable step as ErrInternal", func() {
+			err := runDelSteps(netConf, []delStep{
+				step("first", true, errors.New("busy")),
+				step("second", false, errors.New("gone")),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInternal)))
+			Expect(err.Error()).To(ContainSubstring("first: busy; second: gone"))
+		})
+		It("Skips the steps depending on a failed step", func() {
+			err := runDelSteps(netConf, []delStep{
+				step("VF reset", true, errors.New("busy")),
+				step("VF release", false, nil),
+				step("pci allocation release", true, nil, "VF reset", "VF release"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrTryAgainLater)))
+			Expect(err.Error()).To(ContainSubstring("pci allocation release: skipped as VF reset failed"))
+			Expect(ran).To(Equal([]string{"VF reset", "VF release"}))
+		})
+	})
+	Context("Checking loadPrevResult function", func() {
+		It("Converts the prevResult to the current result type", func() {
+			result, err := loadPrevResult([]byte(`{
+				"cniVersion": "0.4.0",
+				"name": "mynet",
+				"type": "sriov",
+				"prevResult": {
+					"cniVersion": "0.4.0",
+					"interfaces": [{"name": "net1", "mac": "d2:fc:22:a7:0d:e8", "sandbox": "/var/run/netns/pod"}],
+					"ips": [{"version": "4", "address": "10.55.206.10/26", "interface": 0}]
+				}
+			}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Interfaces).To(HaveLen(1))
+			Expect(result.Interfaces[0].Mac).To(Equal("d2:fc:22:a7:0d:e8"))
+			Expect(result.IPs).To(HaveLen(1))
+			Expect(result.IPs[0].Address.String()).To(Equal("10.55.206.10/26"))
+		})
+	})
+})
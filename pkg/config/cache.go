@@ -0,0 +1,79 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	cniutils "github.com/containernetworking/cni/pkg/utils"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+)
+
+// CacheVersion is the version of the cached NetConf format written by this plugin
+// Bump it together with a new entry in cacheMigrations whenever the cached format changes.
+const CacheVersion = 1
+
+// ErrCorruptCache is returned when a cached NetConf can't be decoded
+var ErrCorruptCache = errors.New("cached NetConf is corrupt")
+
+// cacheMigration upgrades the raw fields of a cached NetConf by one version
+type cacheMigration func(fields map[string]json.RawMessage) error
+
+// cacheMigrations maps a cache version to the migration upgrading it to the next version
+var cacheMigrations = map[int]cacheMigration{
+	// Version 0 is the unversioned format written before the cache was versioned, which has the same layout
+	0: func(fields map[string]json.RawMessage) error { return nil },
+}
+
+// SaveConfToCache caches the NetConf of an attachment for the DEL and CHECK commands
+// It is stored under the directory of its network, so attachments of different networks never collide.
+func SaveConfToCache(containerID, ifName string, netConf *sriovtypes.NetConf) error {
+	if err := cniutils.ValidateNetworkName(netConf.Name); err != nil {
+		return err
+	}
+
+	netConf.CacheVersion = CacheVersion
+	return utils.SaveNetConf(containerID, CacheDir(netConf.Name), ifName, netConf)
+}
+
+// decodeCachedConf parses a cached NetConf, migrating it from older cache versions
+func decodeCachedConf(netConfBytes []byte) (*sriovtypes.NetConf, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(netConfBytes, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptCache, err)
+	}
+
+	version := 0
+	if raw, ok := fields["cacheVersion"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("%w: invalid cache version %s: %v", ErrCorruptCache, raw, err)
+		}
+	}
+	if version > CacheVersion {
+		return nil, fmt.Errorf("cached NetConf version %d is newer than the supported version %d", version, CacheVersion)
+	}
+
+	for ; version < CacheVersion; version++ {
+		migrate, ok := cacheMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration for cached NetConf version %d", version)
+		}
+		if err := migrate(fields); err != nil {
+			return nil, fmt.Errorf("failed to migrate cached NetConf from version %d: %v", version, err)
+		}
+	}
+
+	migrated, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize migrated NetConf: %v", err)
+	}
+
+	netConf := &sriovtypes.NetConf{}
+	if err := json.Unmarshal(migrated, netConf); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptCache, err)
+	}
+	netConf.CacheVersion = CacheVersion
+
+	return netConf, nil
+}
@@ -2,11 +2,20 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	cniutils "github.com/containernetworking/cni/pkg/utils"
+	srioverrors "github.com/k8snetworkplumbingwg/sriov-cni/pkg/errors"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
 	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
 )
@@ -14,93 +23,301 @@ import (
 var (
 	// DefaultCNIDir used for caching NetConf
 	DefaultCNIDir = "/var/lib/cni/sriov"
+
+	// devlink rate node names can't be numeric, they would be parsed as port indexes
+	rateGroupRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]*$`)
 )
 
 // LoadConf parses and validates stdin netconf and returns NetConf object
 func LoadConf(bytes []byte) (*sriovtypes.NetConf, error) {
 	n := &sriovtypes.NetConf{}
 	if err := json.Unmarshal(bytes, n); err != nil {
-		return nil, fmt.Errorf("LoadConf(): failed to load netconf: %v", err)
-	}
-
-	// DeviceID takes precedence; if we are given a VF pciaddr then work from there
-	if n.DeviceID != "" {
-		// Get rest of the VF information
-		pfName, vfID, err := getVfInfo(n.DeviceID)
-		if err != nil {
-			return nil, fmt.Errorf("LoadConf(): failed to get VF information: %q", err)
-		}
-		n.VFID = vfID
-		n.Master = pfName
-	} else {
-		return nil, fmt.Errorf("LoadConf(): VF pci addr is required")
+		return nil, srioverrors.Decoding(err, "LoadConf(): failed to load netconf")
 	}
+	clearInternalFields(n)
 
-	allocator := utils.NewPCIAllocator(DefaultCNIDir)
-	// Check if the device is already allocated.
-	// This is to prevent issues where kubelet request to delete a pod and in the same time a new pod using the same
-	// vf is started. we can have an issue where the cmdDel of the old pod is called AFTER the cmdAdd of the new one
-	// This will block the new pod creation until the cmdDel is done.
-	isAllocated, err := allocator.IsAllocated(n.DeviceID)
-	if err != nil {
-		return n, err
+	// DeviceID takes precedence; if we are given a VF pciaddr or an SF auxiliary device then work from there
+	if n.DeviceID == "" {
+		return nil, srioverrors.InvalidConfig("LoadConf(): VF pci addr is required")
 	}
-
-	if isAllocated {
-		return n, fmt.Errorf("pci address %s is already allocated", n.DeviceID)
+	if err := setDeviceInfo(n); err != nil {
+		return nil, srioverrors.InvalidConfig("LoadConf(): failed to get VF information: %q", err)
 	}
 
-	// Assuming VF is netdev interface; Get interface name(s)
-	hostIFNames, err := utils.GetVFLinkNames(n.DeviceID)
-	if err != nil || hostIFNames == "" {
-		// VF interface not found; check if VF has dpdk driver
-		hasDpdkDriver, err := utils.HasDpdkDriver(n.DeviceID)
-		if err != nil {
-			return nil, fmt.Errorf("LoadConf(): failed to detect if VF %s has dpdk driver %q", n.DeviceID, err)
+	// The device is claimed atomically by ADD through PCIAllocator.ClaimPCI, which fails while it is allocated
+	var err error
+	if n.SFNum != nil {
+		// Scalable Functions are always bound to the netdev driver of their PF
+		if n.OrigVfState.HostIFName, err = utils.GetSfLinkName(n.DeviceID); err != nil {
+			return nil, srioverrors.InvalidConfig("LoadConf(): the SF %s does not have a interface name: %v", n.DeviceID, err)
+		}
+	} else if n.VdpaDevice, err = utils.GetVdpaDevice(n.DeviceID); err != nil {
+		return nil, fmt.Errorf("LoadConf(): failed to detect the vDPA device of VF %s: %v", n.DeviceID, err)
+	} else if n.VdpaDevice != nil {
+		if err = setVdpaMode(n); err != nil {
+			return nil, err
+		}
+	} else {
+		// Assuming VF is netdev interface; Get interface name(s)
+		hostIFNames, err := utils.GetVFLinkNames(n.DeviceID)
+		if err != nil || hostIFNames == "" {
+			// VF interface not found; check if VF has dpdk driver
+			hasDpdkDriver, err := utils.HasDpdkDriver(n.DeviceID)
+			if err != nil {
+				return nil, fmt.Errorf("LoadConf(): failed to detect if VF %s has dpdk driver %q", n.DeviceID, err)
+			}
+			n.DPDKMode = hasDpdkDriver
 		}
-		n.DPDKMode = hasDpdkDriver
-	}
 
-	if hostIFNames != "" {
-		n.OrigVfState.HostIFName = hostIFNames
-	}
+		if hostIFNames != "" {
+			n.OrigVfState.HostIFName = hostIFNames
+		}
 
-	if hostIFNames == "" && !n.DPDKMode {
-		return nil, fmt.Errorf("LoadConf(): the VF %s does not have a interface name or a dpdk driver", n.DeviceID)
+		if hostIFNames == "" && !n.DPDKMode {
+			return nil, srioverrors.InvalidConfig("LoadConf(): the VF %s does not have a interface name or a dpdk driver", n.DeviceID)
+		}
 	}
 
 	if n.Vlan != nil {
 		// validate vlan id range
 		if *n.Vlan < 0 || *n.Vlan > 4094 {
-			return nil, fmt.Errorf("LoadConf(): vlan id %d invalid: value must be in the range 0-4094", *n.Vlan)
+			return nil, srioverrors.InvalidConfig("LoadConf(): vlan id %d invalid: value must be in the range 0-4094", *n.Vlan)
 		}
 	}
 
 	if n.VlanQoS != nil {
 		// validate that VLAN QoS is in the 0-7 range
 		if *n.VlanQoS < 0 || *n.VlanQoS > 7 {
-			return nil, fmt.Errorf("LoadConf(): vlan QoS PCP %d invalid: value must be in the range 0-7", *n.VlanQoS)
+			return nil, srioverrors.InvalidConfig("LoadConf(): vlan QoS PCP %d invalid: value must be in the range 0-7", *n.VlanQoS)
 		}
 	}
 
 	// validate that vlan id is set if vlan qos is set
 	if n.VlanQoS != nil && n.Vlan == nil {
-		return nil, fmt.Errorf(("LoadConf(): vlan id must be configured to set vlan QoS"))
+		return nil, srioverrors.InvalidConfig("LoadConf(): vlan id must be configured to set vlan QoS")
 	}
 
 	// validate non-zero value for vlan id if vlan qos is set to a non-zero value
 	if (n.VlanQoS != nil && *n.VlanQoS != 0) && *n.Vlan == 0 {
-		return nil, fmt.Errorf("LoadConf(): non-zero vlan id must be configured to set vlan QoS to a non-zero value")
+		return nil, srioverrors.InvalidConfig("LoadConf(): non-zero vlan id must be configured to set vlan QoS to a non-zero value")
+	}
+
+	// validate that vlan protocol is one of supported values
+	if n.VlanProto != "" {
+		n.VlanProto = strings.ToLower(n.VlanProto)
+		if n.VlanProto != sriovtypes.VlanProto8021q && n.VlanProto != sriovtypes.VlanProto8021ad {
+			return nil, srioverrors.InvalidConfig("LoadConf(): invalid vlanProto value: %s", n.VlanProto)
+		}
+		if n.VlanProto == sriovtypes.VlanProto8021ad && (n.Vlan == nil || *n.Vlan == 0) {
+			return nil, srioverrors.InvalidConfig("LoadConf(): non-zero vlan id must be configured to set vlanProto to %s", n.VlanProto)
+		}
+	}
+
+	// validate the vlan trunk ranges, which replace the single vlan of the VF
+	if n.VlanTrunk != "" {
+		vlanTrunk, err := normalizeVlanTrunk(n.VlanTrunk)
+		if err != nil {
+			return nil, srioverrors.InvalidConfig("LoadConf(): invalid vlanTrunk value %q: %v", n.VlanTrunk, err)
+		}
+		n.VlanTrunk = vlanTrunk
+		if (n.Vlan != nil && *n.Vlan != 0) || n.VlanQoS != nil || n.VlanProto != "" {
+			return nil, srioverrors.InvalidConfig("LoadConf(): vlanTrunk can't be configured along with vlan, vlanQoS or vlanProto")
+		}
+	}
+
+	// validate that the mirror targets are other VFs of the same PF
+	for _, mirror := range []struct {
+		direction string
+		target    *int
+	}{{"ingressMirror", n.IngressMirror}, {"egressMirror", n.EgressMirror}} {
+		direction, target := mirror.direction, mirror.target
+		if target == nil {
+			continue
+		}
+		numVfs, err := utils.GetSriovNumVfs(n.Master)
+		if err != nil {
+			return nil, fmt.Errorf("LoadConf(): failed to get the number of VFs of PF %s: %w", n.Master, err)
+		}
+		if *target < 0 || *target >= numVfs {
+			return nil, srioverrors.InvalidConfig("LoadConf(): %s vf %d invalid: value must be in the range 0-%d", direction, *target, numVfs-1)
+		}
+		if *target == n.VFID {
+			return nil, srioverrors.InvalidConfig("LoadConf(): %s vf %d invalid: a VF can't be mirrored to itself", direction, *target)
+		}
 	}
 
 	// validate that link state is one of supported values
 	if n.LinkState != "" && n.LinkState != "auto" && n.LinkState != "enable" && n.LinkState != "disable" {
-		return nil, fmt.Errorf("LoadConf(): invalid link_state value: %s", n.LinkState)
+		return nil, srioverrors.InvalidConfig("LoadConf(): invalid link_state value: %s", n.LinkState)
+	}
+
+	if n.MTU != nil && *n.MTU <= 0 {
+		return nil, srioverrors.InvalidConfig("LoadConf(): mtu %d invalid: value must be positive", *n.MTU)
+	}
+
+	if n.TxQueueLen != nil && *n.TxQueueLen < 0 {
+		return nil, srioverrors.InvalidConfig("LoadConf(): txQueueLen %d invalid: value can't be negative", *n.TxQueueLen)
+	}
+
+	if n.Promisc != "" && n.Promisc != "on" && n.Promisc != "off" {
+		return nil, srioverrors.InvalidConfig("LoadConf(): invalid promisc value: %s", n.Promisc)
+	}
+
+	if n.Allmulti != "" && n.Allmulti != "on" && n.Allmulti != "off" {
+		return nil, srioverrors.InvalidConfig("LoadConf(): invalid allmulti value: %s", n.Allmulti)
+	}
+
+	if n.Ethtool != nil {
+		for name := range n.Ethtool.Features {
+			if name == "" {
+				return nil, srioverrors.InvalidConfig("LoadConf(): ethtool feature names can't be empty")
+			}
+		}
+		if ring := n.Ethtool.Ring; ring != nil && ((ring.Rx != nil && *ring.Rx == 0) || (ring.Tx != nil && *ring.Tx == 0)) {
+			return nil, srioverrors.InvalidConfig("LoadConf(): ethtool ring sizes must be positive")
+		}
+	}
+
+	for key, value := range n.Sysctl {
+		if _, _, _, err := utils.ParseIfSysctl(key); err != nil {
+			return nil, srioverrors.InvalidConfig("LoadConf(): invalid sysctl: %v", err)
+		}
+		if value == "" {
+			return nil, srioverrors.InvalidConfig("LoadConf(): sysctl %s has no value", key)
+		}
+	}
+
+	// validate the port function attributes, its hwAddr is applied as the mac of the VF
+	if pf := n.PortFunction; pf != nil {
+		if pf.HwAddr != "" {
+			hwAddr, err := net.ParseMAC(pf.HwAddr)
+			if err != nil {
+				return nil, srioverrors.InvalidConfig("LoadConf(): invalid portFunction hwAddr %q: %v", pf.HwAddr, err)
+			}
+			if n.MAC != "" && !strings.EqualFold(n.MAC, hwAddr.String()) {
+				return nil, srioverrors.InvalidConfig("LoadConf(): portFunction hwAddr %s differs from mac %s", pf.HwAddr, n.MAC)
+			}
+			n.MAC = hwAddr.String()
+		}
+		for _, attr := range []struct {
+			name  string
+			value string
+		}{{"roce", pf.Roce}, {"migratable", pf.Migratable}, {"ipsecCrypto", pf.IpsecCrypto}} {
+			if attr.value != "" && attr.value != "on" && attr.value != "off" {
+				return nil, srioverrors.InvalidConfig("LoadConf(): invalid portFunction %s value: %s", attr.name, attr.value)
+			}
+		}
+	}
+
+	if n.RateGroup != "" && !rateGroupRegexp.MatchString(n.RateGroup) {
+		return nil, srioverrors.InvalidConfig("LoadConf(): invalid rateGroup name: %s", n.RateGroup)
+	}
+	if n.TxShare != nil && *n.TxShare < 0 {
+		return nil, srioverrors.InvalidConfig("LoadConf(): txShare %d invalid: value can't be negative", *n.TxShare)
+	}
+	if n.TxMax != nil && *n.TxMax < 0 {
+		return nil, srioverrors.InvalidConfig("LoadConf(): txMax %d invalid: value can't be negative", *n.TxMax)
+	}
+	if n.TxShare != nil && n.TxMax != nil && *n.TxMax > 0 && *n.TxShare > *n.TxMax {
+		return nil, srioverrors.InvalidConfig("LoadConf(): txShare %d can't exceed txMax %d", *n.TxShare, *n.TxMax)
 	}
 
 	return n, nil
 }
 
+// normalizeVlanTrunk validates a comma separated list of VLAN IDs and ranges, e.g. "100-200,300",
+// and returns it without whitespace
+func normalizeVlanTrunk(vlanTrunk string) (string, error) {
+	var ranges []string
+	for _, r := range strings.Split(vlanTrunk, ",") {
+		bounds := strings.Split(strings.TrimSpace(r), "-")
+		if len(bounds) > 2 {
+			return "", fmt.Errorf("invalid range %q", r)
+		}
+		ids := make([]int, 0, len(bounds))
+		for _, bound := range bounds {
+			id, err := strconv.Atoi(strings.TrimSpace(bound))
+			if err != nil {
+				return "", fmt.Errorf("invalid vlan id %q", bound)
+			}
+			if id < 1 || id > 4094 {
+				return "", fmt.Errorf("vlan id %d must be in the range 1-4094", id)
+			}
+			ids = append(ids, id)
+		}
+		if len(ids) == 1 {
+			ranges = append(ranges, strconv.Itoa(ids[0]))
+			continue
+		}
+		if ids[0] > ids[1] {
+			return "", fmt.Errorf("range %q starts after its end", r)
+		}
+		ranges = append(ranges, fmt.Sprintf("%d-%d", ids[0], ids[1]))
+	}
+	return strings.Join(ranges, ","), nil
+}
+
+// clearInternalFields clears the fields of n the plugin sets itself, which are only read from the cached
+// NetConf and must not be set by the stdin netconf
+func clearInternalFields(n *sriovtypes.NetConf) {
+	n.CacheVersion = 0
+	n.CachedResult = nil
+	n.Owner = nil
+	n.OrigVfState = sriovtypes.VfState{}
+	n.Representor = ""
+	n.VdpaDevice = nil
+	n.SFNum = nil
+	n.ContIFNames = ""
+}
+
+// setDeviceInfo sets the PF of the device of n along with its VF ID, or its SF number when the device is the
+// auxiliary device of a Scalable Function
+func setDeviceInfo(n *sriovtypes.NetConf) error {
+	n.SFNum = nil
+	if utils.IsAuxDevice(n.DeviceID) {
+		pfName, sfNum, err := utils.GetSfInfo(n.DeviceID)
+		if err != nil {
+			return err
+		}
+		n.Master = pfName
+		n.SFNum = &sfNum
+		// SFs are not VFs of their PF, keep the VF ID out of the range of its VFs
+		n.VFID = -1
+		return nil
+	}
+
+	pfName, vfID, err := getVfInfo(n.DeviceID)
+	if err != nil {
+		return err
+	}
+	n.VFID = vfID
+	n.Master = pfName
+	return nil
+}
+
+// setVdpaMode sets how the VF is attached from the driver its vDPA device is bound to
+func setVdpaMode(n *sriovtypes.NetConf) error {
+	switch n.VdpaDevice.Driver {
+	case sriovtypes.VdpaDriverVirtio:
+		if n.VdpaDevice.NetDev == "" {
+			return srioverrors.InvalidConfig("LoadConf(): the vDPA device %s of VF %s does not have a interface name",
+				n.VdpaDevice.Name, n.DeviceID)
+		}
+		n.OrigVfState.HostIFName = n.VdpaDevice.NetDev
+	case sriovtypes.VdpaDriverVhost:
+		if n.VdpaDevice.Path == "" {
+			return srioverrors.InvalidConfig("LoadConf(): the vDPA device %s of VF %s does not have a vhost-vdpa device",
+				n.VdpaDevice.Name, n.DeviceID)
+		}
+		// the vhost-vdpa device is handed to the pod like a VF bound to a dpdk driver
+		n.DPDKMode = true
+	default:
+		return srioverrors.InvalidConfig("LoadConf(): the vDPA device %s of VF %s is bound to unsupported driver %q",
+			n.VdpaDevice.Name, n.DeviceID, n.VdpaDevice.Driver)
+	}
+	return nil
+}
+
 func getVfInfo(vfPci string) (string, int, error) {
 	var vfID int
 
@@ -118,25 +335,198 @@ func getVfInfo(vfPci string) (string, int, error) {
 }
 
 // LoadConfFromCache retrieves cached NetConf returns it along with a handle for removal
+// NetConfs cached by older versions are migrated to the current format, corrupt ones return an error
+// wrapping ErrCorruptCache along with the handle. NetConfs cached in the legacy layout, before the cache
+// was split per network, are loaded if they belong to the network of the attachment.
 func LoadConfFromCache(args *skel.CmdArgs) (*sriovtypes.NetConf, string, error) {
-	netConf := &sriovtypes.NetConf{}
+	netName := ""
+	stdinConf := &types.NetConf{}
+	if err := json.Unmarshal(args.StdinData, stdinConf); err == nil && cniutils.ValidateNetworkName(stdinConf.Name) == nil {
+		netName = stdinConf.Name
+	}
 
-	s := []string{args.ContainerID, args.IfName}
-	cRef := strings.Join(s, "-")
-	cRefPath := filepath.Join(DefaultCNIDir, cRef)
+	var cRefPath string
+	var netConfBytes []byte
+	err := os.ErrNotExist
+	if netName != "" {
+		cRefPath = CachePath(netName, args.ContainerID, args.IfName)
+		netConfBytes, err = utils.ReadScratchNetConf(cRefPath)
+	}
+	legacy := errors.Is(err, os.ErrNotExist)
+	if legacy {
+		cRefPath = filepath.Join(DefaultCNIDir, CacheRef(args.ContainerID, args.IfName))
+		netConfBytes, err = utils.ReadScratchNetConf(cRefPath)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading cached NetConf in %s for container %s interface %s: %w",
+			DefaultCNIDir, args.ContainerID, args.IfName, err)
+	}
 
-	netConfBytes, err := utils.ReadScratchNetConf(cRefPath)
+	netConf, err := decodeCachedConf(netConfBytes)
 	if err != nil {
-		return nil, "", fmt.Errorf("error reading cached NetConf in %s with name %s", DefaultCNIDir, cRef)
+		return nil, cRefPath, fmt.Errorf("failed to load cached NetConf %s: %w", cRefPath, err)
+	}
+
+	// The legacy layout is shared by all networks, make sure the entry isn't another network's
+	if legacy && netName != "" && netConf.Name != "" && netConf.Name != netName {
+		return nil, "", fmt.Errorf("cached NetConf %s belongs to network %s, not %s: %w",
+			cRefPath, netConf.Name, netName, os.ErrNotExist)
 	}
 
-	if err = json.Unmarshal(netConfBytes, netConf); err != nil {
-		return nil, "", fmt.Errorf("failed to parse NetConf: %q", err)
+	// DPDKMode is not cached, detect it again. A VF without any driver is not handled as a dpdk one.
+	if netConf.VdpaDevice != nil {
+		netConf.DPDKMode = netConf.VdpaDevice.Driver == sriovtypes.VdpaDriverVhost
+	} else if netConf.SFNum == nil {
+		netConf.DPDKMode, _ = utils.HasDpdkDriver(netConf.DeviceID)
 	}
 
 	return netConf, cRefPath, nil
 }
 
+// LoadConfWithoutCache builds the NetConf needed to release the VF of an attachment from the stdin netconf,
+// for when its cached NetConf is missing or unusable. The VF is taken from deviceID when the stdin netconf
+// doesn't carry one. As the original VF state is unknown, OrigVfState is left empty.
+func LoadConfWithoutCache(bytes []byte, deviceID string) (*sriovtypes.NetConf, error) {
+	n := &sriovtypes.NetConf{}
+	if err := json.Unmarshal(bytes, n); err != nil {
+		return nil, fmt.Errorf("LoadConfWithoutCache(): failed to load netconf: %v", err)
+	}
+	clearInternalFields(n)
+
+	if n.DeviceID == "" {
+		n.DeviceID = deviceID
+	}
+	if n.DeviceID == "" {
+		return nil, fmt.Errorf("LoadConfWithoutCache(): VF pci addr is unknown")
+	}
+
+	if err := setDeviceInfo(n); err != nil {
+		return nil, fmt.Errorf("LoadConfWithoutCache(): failed to get VF information: %q", err)
+	}
+
+	// Scalable Functions always have a netdev
+	if n.SFNum != nil {
+		return n, nil
+	}
+
+	// The netdev of a vDPA device only exists with virtio_vdpa
+	if vdpaDev, err := utils.GetVdpaDevice(n.DeviceID); err == nil && vdpaDev != nil {
+		n.VdpaDevice = vdpaDev
+		n.DPDKMode = vdpaDev.Driver != sriovtypes.VdpaDriverVirtio
+		return n, nil
+	}
+
+	// A VF that isn't bound to any driver has no netdev to release either, so it is handled as a dpdk one
+	if hasDpdkDriver, err := utils.HasDpdkDriver(n.DeviceID); err != nil || hasDpdkDriver {
+		n.DPDKMode = true
+	}
+
+	return n, nil
+}
+
+// LoadGCConf parses the netconf passed to the CNI GC command
+func LoadGCConf(bytes []byte) (*types.NetConf, error) {
+	n := &types.NetConf{}
+	if err := json.Unmarshal(bytes, n); err != nil {
+		return nil, srioverrors.Decoding(err, "LoadGCConf(): failed to load netconf")
+	}
+	return n, nil
+}
+
+// CacheRef returns the name under which the NetConf of an attachment is cached
+func CacheRef(containerID, ifName string) string {
+	return strings.Join([]string{containerID, ifName}, "-")
+}
+
+// CacheDir returns the directory where the NetConfs of the network netName are cached
+func CacheDir(netName string) string {
+	return filepath.Join(DefaultCNIDir, "networks", netName)
+}
+
+// CachePath returns the path where the NetConf of an attachment to the network netName is cached
+func CachePath(netName, containerID, ifName string) string {
+	return filepath.Join(CacheDir(netName), CacheRef(containerID, ifName))
+}
+
+// LoadAllConfsFromCache retrieves every cached NetConf of the network netName, keyed by its cache path
+// Both the network cache directory and the legacy layout are searched.
+func LoadAllConfsFromCache(netName string) (map[string]*sriovtypes.NetConf, error) {
+	confs := make(map[string]*sriovtypes.NetConf)
+	for _, dir := range []string{CacheDir(netName), DefaultCNIDir} {
+		if err := loadConfsFromDir(dir, netName, confs); err != nil {
+			return nil, err
+		}
+	}
+	return confs, nil
+}
+
+// loadConfsFromDir adds the NetConfs of the network netName cached in dir to confs
+func loadConfsFromDir(dir, netName string, confs map[string]*sriovtypes.NetConf) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error reading cache directory %s: %v", dir, err)
+	}
+
+	for _, entry := range entries {
+		// Hidden files are temporary files of cache writes in progress
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		// An entry removed by a concurrent DEL is skipped, and an unreadable one must not prevent the others
+		// from being loaded
+		cRefPath := filepath.Join(dir, entry.Name())
+		netConfBytes, err := utils.ReadScratchNetConf(cRefPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			logging.Warning("Skipping unreadable cached netconf", "path", cRefPath, "error", err)
+			continue
+		}
+
+		// Entries that can't be parsed can't be attributed to a network either; leave them alone
+		netConf, err := decodeCachedConf(netConfBytes)
+		if err != nil {
+			logging.Warning("Skipping undecodable cached netconf", "path", cRefPath, "error", err)
+			continue
+		}
+
+		if netConf.Name == netName {
+			confs[cRefPath] = netConf
+		}
+	}
+
+	return nil
+}
+
+// GetVdpaDeviceInfo returns the device-info of the attachment, or nil if the VF isn't used through a vDPA device
+func GetVdpaDeviceInfo(netConf *sriovtypes.NetConf) (*sriovtypes.DeviceInfo, error) {
+	if netConf.VdpaDevice == nil {
+		return nil, nil
+	}
+
+	pfPciAddr, err := utils.GetPfPciAddress(netConf.Master)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sriovtypes.DeviceInfo{
+		Type:    "vdpa",
+		Version: "1.1.0",
+		Vdpa: &sriovtypes.VdpaDeviceInfo{
+			ParentDevice: netConf.VdpaDevice.Name,
+			Driver:       strings.TrimSuffix(netConf.VdpaDevice.Driver, "_vdpa"),
+			Path:         netConf.VdpaDevice.Path,
+			PciAddress:   netConf.DeviceID,
+			PfPciAddress: pfPciAddr,
+		},
+	}, nil
+}
+
 // GetMacAddressForResult return the mac address we should report to the CNI call return object
 // if the device is on kernel mode we report that one back
 // if not we check the administrative mac address on the PF
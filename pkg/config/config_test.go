@@ -1,12 +1,17 @@
 package config
 
 import (
+	"fmt"
+	"github.com/containernetworking/cni/pkg/skel"
+	cnitypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/plugins/pkg/testutils"
+	srioverrors "github.com/k8snetworkplumbingwg/sriov-cni/pkg/errors"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"os"
+	"path/filepath"
 )
 
 var _ = Describe("Config", func() {
@@ -29,6 +34,75 @@ var _ = Describe("Config", func() {
 			_, err := LoadConf(conf)
 			Expect(err).NotTo(HaveOccurred())
 		})
+		It("Assuming correct config file - Scalable Function DeviceID", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "mlx5_core.sf.4"
+                        }`)
+			netConf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConf.Master).To(Equal("enp175s0f1"))
+			Expect(*netConf.SFNum).To(Equal(4))
+			Expect(netConf.OrigVfState.HostIFName).To(Equal("enp175s0f1s4"))
+			Expect(netConf.DPDKMode).To(BeFalse())
+		})
+		It("Ignores the internal fields set in the stdin netconf", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "mlx5_core.sf.4",
+        "representor": "eth0",
+        "vdpaDevice": {"name": "vdpa0", "driver": "vhost_vdpa", "path": "/dev/vhost-vdpa-0"},
+        "owner": {"containerID": "other", "ifName": "net1"},
+        "cachedResult": {"cniVersion": "1.0.0"},
+        "OrigVfState": {"HostIFName": "eth0"}
+                        }`)
+			netConf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConf.Representor).To(BeEmpty())
+			Expect(netConf.VdpaDevice).To(BeNil())
+			Expect(netConf.Owner).To(BeNil())
+			Expect(netConf.CachedResult).To(BeNil())
+			Expect(netConf.OrigVfState.HostIFName).To(Equal("enp175s0f1s4"))
+		})
+		It("Assuming correct config file - VF bound to virtio_vdpa", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:3b:00.2"
+                        }`)
+			netConf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConf.Master).To(Equal("ens2f0"))
+			Expect(netConf.VFID).To(Equal(0))
+			Expect(netConf.VdpaDevice.Name).To(Equal("vdpa0"))
+			Expect(netConf.OrigVfState.HostIFName).To(Equal("eth0"))
+			Expect(netConf.DPDKMode).To(BeFalse())
+		})
+		It("Assuming correct config file - VF bound to vhost_vdpa", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:3b:00.3"
+                        }`)
+			netConf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConf.VFID).To(Equal(1))
+			Expect(netConf.VdpaDevice.Path).To(Equal("/dev/vhost-vdpa-1"))
+			Expect(netConf.OrigVfState.HostIFName).To(BeEmpty())
+			Expect(netConf.DPDKMode).To(BeTrue())
+		})
+		It("Assuming incorrect config file - not existing Scalable Function DeviceID", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "mlx5_core.sf.5"
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+		})
 		It("Assuming incorrect config file - not existing DeviceID", func() {
 			conf := []byte(`{
         "name": "mynet",
@@ -47,6 +121,150 @@ var _ = Describe("Config", func() {
 			_, err := LoadConf(conf)
 			Expect(err).To(HaveOccurred())
 		})
+		It("Assuming correct config file - vlan protocol 802.1ad", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vlan": 100,
+        "vlanProto": "802.1AD"
+                        }`)
+			netConf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConf.VlanProto).To(Equal(types.VlanProto8021ad))
+		})
+		It("Assuming incorrect config file - invalid vlan protocol", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vlan": 100,
+        "vlanProto": "802.1x"
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+		})
+		It("Assuming incorrect config file - vlan protocol 802.1ad without vlan", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vlanProto": "802.1ad"
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Assuming correct config file - vlan trunk", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vlanTrunk": "100 - 200, 300"
+                        }`)
+			netConf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConf.VlanTrunk).To(Equal("100-200,300"))
+		})
+		DescribeTable("Assuming incorrect config file - invalid vlan trunk",
+			func(vlanTrunk string) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vlanTrunk": %q
+                        }`, vlanTrunk))
+				_, err := LoadConf(conf)
+				Expect(err).To(HaveOccurred())
+				Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+			},
+			Entry("out of range vlan id", "100,4095"),
+			Entry("zero vlan id", "0-10"),
+			Entry("reversed range", "200-100"),
+			Entry("not a number", "100,abc"),
+			Entry("empty range", "100,,200"),
+			Entry("too many bounds", "1-2-3"),
+		)
+		It("Assuming incorrect config file - vlan trunk along with vlan", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "vlan": 100,
+        "vlanTrunk": "200-300"
+                        }`)
+			_, err := LoadConf(conf)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Assuming correct config file - mirror to another VF", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "ingressMirror": 0,
+        "egressMirror": 0
+                        }`)
+			netConf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*netConf.IngressMirror).To(Equal(0))
+			Expect(*netConf.EgressMirror).To(Equal(0))
+		})
+		DescribeTable("Assuming incorrect config file - invalid mirror",
+			func(field string, target int) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        %q: %d
+                        }`, field, target))
+				_, err := LoadConf(conf)
+				Expect(err).To(HaveOccurred())
+				Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+			},
+			Entry("ingress mirror beyond sriov_numvfs", "ingressMirror", 2),
+			Entry("negative egress mirror", "egressMirror", -1),
+			Entry("mirror to itself", "egressMirror", 1),
+		)
+		It("Assuming correct config file - port function hwAddr applied as the mac", func() {
+			conf := []byte(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        "portFunction": {"hwAddr": "CA:FE:C0:FF:EE:00", "roce": "off"}
+                        }`)
+			netConf, err := LoadConf(conf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netConf.MAC).To(Equal("ca:fe:c0:ff:ee:00"))
+			Expect(netConf.PortFunction.Roce).To(Equal("off"))
+		})
+		DescribeTable("Assuming incorrect config file - invalid pod interface attributes",
+			func(attr string) {
+				conf := []byte(fmt.Sprintf(`{
+        "name": "mynet",
+        "type": "sriov",
+        "deviceID": "0000:af:06.1",
+        %s
+                        }`, attr))
+				_, err := LoadConf(conf)
+				Expect(err).To(HaveOccurred())
+				Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+			},
+			Entry("zero mtu", `"mtu": 0`),
+			Entry("negative txQueueLen", `"txQueueLen": -1`),
+			Entry("invalid promisc", `"promisc": "yes"`),
+			Entry("invalid allmulti", `"allmulti": "true"`),
+			Entry("empty ethtool feature name", `"ethtool": {"features": {"": true}}`),
+			Entry("zero ethtool ring size", `"ethtool": {"ring": {"rx": 0}}`),
+			Entry("sysctl not under an interface", `"sysctl": {"net.ipv4.ip_forward": "1"}`),
+			Entry("empty sysctl value", `"sysctl": {"net.ipv4.conf.IFNAME.rp_filter": ""}`),
+			Entry("invalid port function hwAddr", `"portFunction": {"hwAddr": "not-a-mac"}`),
+			Entry("port function hwAddr differing from mac", `"mac": "ca:fe:c0:ff:ee:01", "portFunction": {"hwAddr": "ca:fe:c0:ff:ee:00"}`),
+			Entry("invalid port function roce", `"portFunction": {"roce": "enable"}`),
+			Entry("numeric rate group", `"rateGroup": "1"`),
+			Entry("rate group with a slash", `"rateGroup": "tenant/a"`),
+			Entry("negative txShare", `"txShare": -1`),
+			Entry("txShare exceeding txMax", `"txShare": 200, "txMax": 100`),
+		)
 		It("Assuming incorrect config file - broken json", func() {
 			conf := []byte(`{
         "name": "mynet"
@@ -66,7 +284,7 @@ var _ = Describe("Config", func() {
 			Expect(err).To(HaveOccurred())
 		})
 
-		It("Assuming device is allocated", func() {
+		It("Leaves the allocation check of the device to ClaimPCI", func() {
 			conf := []byte(`{
         "name": "mynet",
         "type": "sriov",
@@ -100,14 +318,199 @@ var _ = Describe("Config", func() {
 			}()
 
 			allocator := utils.NewPCIAllocator(tmpdir)
-			err = allocator.SaveAllocatedPCI("0000:af:06.1", targetNetNS.Path())
+			err = allocator.ClaimPCI("0000:af:06.1", utils.NewPCIOwner("cid", "net1", targetNetNS.Path()))
 			Expect(err).ToNot(HaveOccurred())
 
 			_, err = LoadConf(conf)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+	})
+	Context("Checking LoadAllConfsFromCache function", func() {
+		It("Returns only the cached netconfs of the requested network", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-testfiles-")
+			Expect(err).ToNot(HaveOccurred())
+			originCNIDir := DefaultCNIDir
+			DefaultCNIDir = tmpdir
+			defer func() {
+				DefaultCNIDir = originCNIDir
+				os.RemoveAll(tmpdir)
+			}()
+
+			mynet := &types.NetConf{DeviceID: "0000:af:06.0"}
+			mynet.Name = "mynet"
+			othernet := &types.NetConf{DeviceID: "0000:af:06.1"}
+			othernet.Name = "othernet"
+			Expect(SaveConfToCache("cid1", "net1", mynet)).To(Succeed())
+			Expect(SaveConfToCache("cid2", "net1", othernet)).To(Succeed())
+			legacy := &types.NetConf{DeviceID: "0000:af:06.1"}
+			legacy.Name = "mynet"
+			Expect(utils.SaveNetConf("cid3", tmpdir, "net1", legacy)).To(Succeed())
+			Expect(utils.NewPCIAllocator(tmpdir).ClaimPCI("0000:af:06.0", utils.NewPCIOwner("cid1", "net1", "/proc/self/ns/net"))).To(Succeed())
+
+			confs, err := LoadAllConfsFromCache("mynet")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(HaveLen(2))
+			Expect(confs).To(HaveKey(filepath.Join(tmpdir, "networks", "mynet", "cid1-net1")))
+			Expect(confs[filepath.Join(tmpdir, "networks", "mynet", "cid1-net1")].DeviceID).To(Equal("0000:af:06.0"))
+			Expect(confs).To(HaveKey(filepath.Join(tmpdir, "cid3-net1")))
+		})
+		It("Skips the cached netconfs it can't decode", func() {
+			tmpdir, err := os.MkdirTemp("/tmp", "sriovplugin-testfiles-")
+			Expect(err).ToNot(HaveOccurred())
+			originCNIDir := DefaultCNIDir
+			DefaultCNIDir = tmpdir
+			defer func() {
+				DefaultCNIDir = originCNIDir
+				os.RemoveAll(tmpdir)
+			}()
+
+			mynet := &types.NetConf{DeviceID: "0000:af:06.0"}
+			mynet.Name = "mynet"
+			Expect(SaveConfToCache("cid1", "net1", mynet)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(CacheDir("mynet"), "cid2-net1"), []byte(`{"name":"myn`), 0o600)).To(Succeed())
+
+			confs, err := LoadAllConfsFromCache("mynet")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(confs).To(HaveLen(1))
+			Expect(confs).To(HaveKey(filepath.Join(tmpdir, "networks", "mynet", "cid1-net1")))
+		})
+	})
+	Context("Checking LoadConfFromCache function", func() {
+		var tmpdir string
+		var originCNIDir string
+		args := &skel.CmdArgs{ContainerID: "cid", IfName: "net1", StdinData: []byte(`{"name":"mynet","type":"sriov"}`)}
+
+		BeforeEach(func() {
+			var err error
+			tmpdir, err = os.MkdirTemp("/tmp", "sriovplugin-testfiles-")
+			Expect(err).ToNot(HaveOccurred())
+			originCNIDir = DefaultCNIDir
+			DefaultCNIDir = tmpdir
+		})
+		AfterEach(func() {
+			DefaultCNIDir = originCNIDir
+			os.RemoveAll(tmpdir)
+		})
+
+		It("Loads a NetConf cached by SaveConfToCache", func() {
+			netConf := &types.NetConf{DeviceID: "0000:af:06.0"}
+			netConf.Name = "mynet"
+			Expect(SaveConfToCache("cid", "net1", netConf)).To(Succeed())
+
+			cached, cRefPath, err := LoadConfFromCache(args)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cRefPath).To(Equal(filepath.Join(tmpdir, "networks", "mynet", "cid-net1")))
+			Expect(cached.CacheVersion).To(Equal(CacheVersion))
+			Expect(cached.DeviceID).To(Equal("0000:af:06.0"))
+		})
+		It("Loads the cached result along with the NetConf", func() {
+			netConf := &types.NetConf{DeviceID: "0000:af:06.0", CachedResult: []byte(`{"cniVersion":"1.0.0","interfaces":[{"name":"net1"}]}`)}
+			netConf.Name = "mynet"
+			Expect(SaveConfToCache("cid", "net1", netConf)).To(Succeed())
+
+			cached, _, err := LoadConfFromCache(args)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.CachedResult).To(MatchJSON(`{"cniVersion":"1.0.0","interfaces":[{"name":"net1"}]}`))
+		})
+		It("Keeps the NetConfs of different networks apart", func() {
+			netConf := &types.NetConf{DeviceID: "0000:af:06.0"}
+			netConf.Name = "mynet"
+			Expect(SaveConfToCache("cid", "net1", netConf)).To(Succeed())
+			othernet := &types.NetConf{DeviceID: "0000:af:06.1"}
+			othernet.Name = "othernet"
+			Expect(SaveConfToCache("cid", "net1", othernet)).To(Succeed())
+
+			cached, _, err := LoadConfFromCache(args)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.DeviceID).To(Equal("0000:af:06.0"))
+		})
+		It("Falls back to the legacy cache layout", func() {
+			Expect(os.WriteFile(filepath.Join(tmpdir, "cid-net1"), []byte(`{"name":"mynet","deviceID":"0000:af:06.0"}`), 0600)).To(Succeed())
+
+			cached, cRefPath, err := LoadConfFromCache(args)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cRefPath).To(Equal(filepath.Join(tmpdir, "cid-net1")))
+			Expect(cached.DeviceID).To(Equal("0000:af:06.0"))
+		})
+		It("Ignores a legacy NetConf of another network", func() {
+			Expect(os.WriteFile(filepath.Join(tmpdir, "cid-net1"), []byte(`{"name":"othernet","deviceID":"0000:af:06.0"}`), 0600)).To(Succeed())
+
+			_, _, err := LoadConfFromCache(args)
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+		It("Migrates an unversioned NetConf", func() {
+			Expect(os.WriteFile(filepath.Join(tmpdir, "cid-net1"), []byte(`{"deviceID":"0000:af:06.0","OrigVfState":{"HostIFName":"enp175s6"}}`), 0600)).To(Succeed())
+
+			cached, _, err := LoadConfFromCache(args)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.CacheVersion).To(Equal(CacheVersion))
+			Expect(cached.DeviceID).To(Equal("0000:af:06.0"))
+			Expect(cached.OrigVfState.HostIFName).To(Equal("enp175s6"))
+		})
+		It("Reports a truncated NetConf as corrupt", func() {
+			Expect(os.WriteFile(filepath.Join(tmpdir, "cid-net1"), []byte(`{"deviceID":"0000:af`), 0600)).To(Succeed())
+
+			_, cRefPath, err := LoadConfFromCache(args)
+			Expect(err).To(MatchError(ErrCorruptCache))
+			Expect(cRefPath).To(Equal(filepath.Join(tmpdir, "cid-net1")))
+		})
+		It("Refuses a NetConf cached by a newer version", func() {
+			Expect(os.WriteFile(filepath.Join(tmpdir, "cid-net1"), []byte(`{"cacheVersion":99}`), 0600)).To(Succeed())
+
+			_, _, err := LoadConfFromCache(args)
 			Expect(err).To(HaveOccurred())
-			Expect(err.Error()).To(ContainSubstring("pci address 0000:af:06.1 is already allocated"))
+			Expect(err.Error()).To(ContainSubstring("newer than the supported version"))
 		})
+		It("Handles a VF bound to vhost_vdpa as a dpdk one", func() {
+			netConf := &types.NetConf{DeviceID: "0000:3b:00.3", VdpaDevice: &types.VdpaDevice{Name: "vdpa1", Driver: types.VdpaDriverVhost}}
+			netConf.Name = "mynet"
+			Expect(SaveConfToCache("cid", "net1", netConf)).To(Succeed())
 
+			cached, _, err := LoadConfFromCache(args)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.DPDKMode).To(BeTrue())
+		})
+		It("Reports a missing NetConf as not existing", func() {
+			_, _, err := LoadConfFromCache(args)
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+	})
+	Context("Checking LoadConfWithoutCache function", func() {
+		It("Resolves the VF from the stdin netconf", func() {
+			netConf, err := LoadConfWithoutCache([]byte(`{"name":"mynet","type":"sriov","deviceID":"0000:af:06.1"}`), "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netConf.Master).To(Equal("enp175s0f1"))
+			Expect(netConf.VFID).To(Equal(1))
+		})
+		It("Falls back to the given pci address", func() {
+			netConf, err := LoadConfWithoutCache([]byte(`{"name":"mynet","type":"sriov"}`), "0000:af:06.0")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netConf.DeviceID).To(Equal("0000:af:06.0"))
+			Expect(netConf.VFID).To(Equal(0))
+		})
+		It("Resolves a Scalable Function", func() {
+			netConf, err := LoadConfWithoutCache([]byte(`{"name":"mynet","type":"sriov"}`), "mlx5_core.sf.4")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netConf.Master).To(Equal("enp175s0f1"))
+			Expect(*netConf.SFNum).To(Equal(4))
+			Expect(netConf.DPDKMode).To(BeFalse())
+		})
+		It("Detects the vDPA device of the VF", func() {
+			netConf, err := LoadConfWithoutCache([]byte(`{"name":"mynet","type":"sriov"}`), "0000:3b:00.2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netConf.VdpaDevice.Driver).To(Equal(types.VdpaDriverVirtio))
+			Expect(netConf.DPDKMode).To(BeFalse())
+
+			netConf, err = LoadConfWithoutCache([]byte(`{"name":"mynet","type":"sriov"}`), "0000:3b:00.3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netConf.VdpaDevice.Driver).To(Equal(types.VdpaDriverVhost))
+			Expect(netConf.DPDKMode).To(BeTrue())
+		})
+		It("Fails when the VF is unknown", func() {
+			_, err := LoadConfWithoutCache([]byte(`{"name":"mynet","type":"sriov"}`), "")
+			Expect(err).To(HaveOccurred())
+		})
 	})
 	Context("Checking getVfInfo function", func() {
 		It("Assuming existing PF", func() {
@@ -119,6 +522,26 @@ var _ = Describe("Config", func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+	Context("Checking GetVdpaDeviceInfo function", func() {
+		It("Returns the device-info of a vDPA device", func() {
+			netConf := &types.NetConf{DeviceID: "0000:3b:00.3", Master: "ens2f0",
+				VdpaDevice: &types.VdpaDevice{Name: "vdpa1", Driver: types.VdpaDriverVhost, Path: "/dev/vhost-vdpa-1"}}
+			devInfo, err := GetVdpaDeviceInfo(netConf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devInfo).To(Equal(&types.DeviceInfo{Type: "vdpa", Version: "1.1.0", Vdpa: &types.VdpaDeviceInfo{
+				ParentDevice: "vdpa1",
+				Driver:       "vhost",
+				Path:         "/dev/vhost-vdpa-1",
+				PciAddress:   "0000:3b:00.3",
+				PfPciAddress: "0000:3b:00.0",
+			}}))
+		})
+		It("Returns nil without vDPA device", func() {
+			devInfo, err := GetVdpaDeviceInfo(&types.NetConf{DeviceID: "0000:af:06.0", Master: "enp175s0f1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devInfo).To(BeNil())
+		})
+	})
 	Context("Checking GetMacAddressForResult function", func() {
 		It("Should return the mac address requested by the user", func() {
 			netconf := &types.NetConf{
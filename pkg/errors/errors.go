@@ -0,0 +1,107 @@
+// Package errors provides the typed errors of the plugin and maps them to CNI error codes, so that runtimes
+// can tell failures worth retrying apart from configuration errors.
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/containernetworking/cni/pkg/types"
+)
+
+// Error is a plugin error carrying the CNI error code it is reported with
+type Error struct {
+	Code uint
+	Msg  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s: %v", e.Msg, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error reported with the CNI error code, wrapping err if not nil
+func New(code uint, err error, format string, a ...interface{}) error {
+	return &Error{Code: code, Msg: fmt.Sprintf(format, a...), Err: err}
+}
+
+// AlreadyAllocated returns the error of a VF that is in use by another attachment
+// It is reported as try again later, since the VF is freed once the other attachment is deleted.
+func AlreadyAllocated(pciAddress string) error {
+	return New(types.ErrTryAgainLater, nil, "pci address %s is already allocated", pciAddress)
+}
+
+// InvalidConfig returns the error of a netconf that fails validation
+func InvalidConfig(format string, a ...interface{}) error {
+	return New(types.ErrInvalidNetworkConfig, nil, format, a...)
+}
+
+// Decoding returns the error of a netconf that can't be parsed
+func Decoding(err error, format string, a ...interface{}) error {
+	return New(types.ErrDecodingFailure, err, format, a...)
+}
+
+// Netlink returns the error of a netlink request setting attr of the VF vfID through the PF pfName, bound to
+// the driver pfDriver. Errors caused by the driver are explained and mapped to the matching CNI error code.
+func Netlink(err error, pfName, pfDriver string, vfID int, attr string) error {
+	switch {
+	case errors.Is(err, syscall.EOPNOTSUPP):
+		return New(types.ErrInvalidNetworkConfig, err, "driver %s of PF %s does not support setting %s of vf %d", pfDriver, pfName, attr, vfID)
+	case errors.Is(err, syscall.EBUSY):
+		return New(types.ErrTryAgainLater, err, "driver %s of PF %s is busy setting %s of vf %d", pfDriver, pfName, attr, vfID)
+	}
+	return New(types.ErrInternal, err, "failed to set %s of vf %d on PF %s (driver %s)", attr, vfID, pfName, pfDriver)
+}
+
+// Ethtool returns the error of an ethtool request setting attr of the netdev ifName, bound to the driver driver.
+// Settings the driver doesn't support or rejects are explained and mapped to the matching CNI error code.
+func Ethtool(err error, ifName, driver, attr string) error {
+	switch {
+	case errors.Is(err, syscall.EOPNOTSUPP):
+		return New(types.ErrInvalidNetworkConfig, err, "driver %s of %s does not support setting %s", driver, ifName, attr)
+	case errors.Is(err, syscall.EINVAL):
+		return New(types.ErrInvalidNetworkConfig, err, "driver %s of %s rejected %s", driver, ifName, attr)
+	case errors.Is(err, syscall.EBUSY):
+		return New(types.ErrTryAgainLater, err, "driver %s of %s is busy setting %s", driver, ifName, attr)
+	}
+	return New(types.ErrInternal, err, "failed to set %s of %s (driver %s)", attr, ifName, driver)
+}
+
+// Unsupported returns the error of a VF attribute attr that the driver pfDriver of the PF pfName doesn't expose
+func Unsupported(err error, pfName, pfDriver string, vfID int, attr string) error {
+	return New(types.ErrInvalidNetworkConfig, err, "driver %s of PF %s does not support %s of vf %d", pfDriver, pfName, attr, vfID)
+}
+
+// Sysfs returns the error of a write to the driver specific sysfs attribute setting attr of the VF vfID of
+// the PF pfName, bound to the driver pfDriver. Attributes missing from sysfs are reported as unsupported.
+func Sysfs(err error, pfName, pfDriver string, vfID int, attr string) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return Unsupported(err, pfName, pfDriver, vfID, attr)
+	}
+	return New(types.ErrInternal, err, "failed to set %s of vf %d on PF %s (driver %s)", attr, vfID, pfName, pfDriver)
+}
+
+// ToCNI converts err to the CNI error it is reported with
+// The message of typed errors becomes the CNI error message and the full error chain its details.
+func ToCNI(err error) *types.Error {
+	var cniErr *types.Error
+	if errors.As(err, &cniErr) {
+		return cniErr
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return types.NewError(e.Code, e.Msg, err.Error())
+	}
+
+	return types.NewError(types.ErrInternal, err.Error(), "")
+}
@@ -0,0 +1,78 @@
+package errors
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/containernetworking/cni/pkg/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Errors", func() {
+	Context("Checking ToCNI function", func() {
+		It("Reports an allocated VF as try again later", func() {
+			err := fmt.Errorf("failed to claim: %w", AlreadyAllocated("0000:af:06.0"))
+			cniErr := ToCNI(err)
+			Expect(cniErr.Code).To(Equal(uint(types.ErrTryAgainLater)))
+			Expect(cniErr.Msg).To(Equal("pci address 0000:af:06.0 is already allocated"))
+			Expect(cniErr.Details).To(Equal("failed to claim: pci address 0000:af:06.0 is already allocated"))
+		})
+		It("Reports validation failures as invalid config", func() {
+			cniErr := ToCNI(InvalidConfig("invalid link_state value: %s", "up"))
+			Expect(cniErr.Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+		})
+		It("Keeps CNI errors as they are", func() {
+			err := types.NewError(types.ErrUnknownContainer, "unknown", "details")
+			Expect(ToCNI(fmt.Errorf("wrapped: %w", err))).To(Equal(err))
+		})
+		It("Reports untyped errors as internal", func() {
+			cniErr := ToCNI(fmt.Errorf("boom"))
+			Expect(cniErr.Code).To(Equal(uint(types.ErrInternal)))
+			Expect(cniErr.Msg).To(Equal("boom"))
+		})
+	})
+	Context("Checking Netlink function", func() {
+		It("Explains an unsupported attribute", func() {
+			err := Netlink(syscall.EOPNOTSUPP, "enp175s0f1", "ixgbe", 1, "trust on")
+			Expect(err.Error()).To(ContainSubstring("driver ixgbe of PF enp175s0f1 does not support setting trust on of vf 1"))
+			Expect(ToCNI(err).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+			Expect(err).To(MatchError(syscall.EOPNOTSUPP))
+		})
+		It("Reports a busy device as try again later", func() {
+			err := Netlink(syscall.EBUSY, "enp175s0f1", "mlx5_core", 0, "vlan")
+			Expect(ToCNI(err).Code).To(Equal(uint(types.ErrTryAgainLater)))
+		})
+		It("Reports other errnos as internal", func() {
+			err := Netlink(syscall.EINVAL, "enp175s0f1", "mlx5_core", 0, "vlan")
+			Expect(ToCNI(err).Code).To(Equal(uint(types.ErrInternal)))
+		})
+	})
+	Context("Checking Sysfs function", func() {
+		It("Reports a missing attribute as unsupported", func() {
+			err := Sysfs(fmt.Errorf("failed to open trunk: %w", os.ErrNotExist), "enp175s0f1", "ixgbe", 1, "vlanTrunk")
+			Expect(err.Error()).To(ContainSubstring("driver ixgbe of PF enp175s0f1 does not support vlanTrunk of vf 1"))
+			Expect(ToCNI(err).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+		})
+		It("Reports other failures as internal", func() {
+			err := Sysfs(syscall.EINVAL, "enp175s0f1", "i40e", 0, "vlanTrunk")
+			Expect(ToCNI(err).Code).To(Equal(uint(types.ErrInternal)))
+		})
+	})
+	Context("Checking Ethtool function", func() {
+		It("Explains an unsupported setting", func() {
+			err := Ethtool(fmt.Errorf("unknown feature rx-lro: %w", syscall.EOPNOTSUPP), "net1", "iavf", "features")
+			Expect(err.Error()).To(ContainSubstring("driver iavf of net1 does not support setting features"))
+			Expect(ToCNI(err).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+		})
+		It("Reports a rejected value as invalid config", func() {
+			err := Ethtool(syscall.EINVAL, "net1", "iavf", "ring sizes")
+			Expect(ToCNI(err).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+		})
+		It("Reports other errnos as internal", func() {
+			err := Ethtool(syscall.ENODEV, "net1", "iavf", "channel counts")
+			Expect(ToCNI(err).Code).To(Equal(uint(types.ErrInternal)))
+		})
+	})
+})
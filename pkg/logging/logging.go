@@ -0,0 +1,161 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level type
+type Level uint32
+
+// Logging levels, from the least to the most verbose
+const (
+	InvalidLevel Level = iota
+	ErrorLevel
+	WarningLevel
+	InfoLevel
+	DebugLevel
+)
+
+const (
+	defaultLevel      = InfoLevel
+	logTimestampFmt   = time.RFC3339Nano
+	logFilePermission = 0600
+)
+
+var levelNames = map[Level]string{
+	ErrorLevel:   "error",
+	WarningLevel: "warning",
+	InfoLevel:    "info",
+	DebugLevel:   "debug",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseLevel returns the logging level matching the given name or InvalidLevel if there is none
+func ParseLevel(name string) Level {
+	switch strings.ToLower(name) {
+	case "error":
+		return ErrorLevel
+	case "warning", "warn":
+		return WarningLevel
+	case "info":
+		return InfoLevel
+	case "debug":
+		return DebugLevel
+	}
+	return InvalidLevel
+}
+
+type logger struct {
+	mu      sync.Mutex
+	level   Level
+	logFile string
+	stderr  io.Writer
+	// fields added to every message, identifying the plugin invocation
+	context []interface{}
+}
+
+var std = &logger{level: defaultLevel, stderr: os.Stderr}
+
+// Init configures the logger for a plugin invocation. Messages are appended to logFile, or written to stderr
+// when logFile is empty or can't be opened. The container ID, netns and interface name are added to every message.
+func Init(logLevel, logFile, containerID, netns, ifName string) {
+	std.mu.Lock()
+	std.level = defaultLevel
+	std.logFile = logFile
+	std.context = []interface{}{"containerID", containerID, "netns", netns, "ifname", ifName}
+	std.mu.Unlock()
+
+	if logLevel != "" {
+		level := ParseLevel(logLevel)
+		if level == InvalidLevel {
+			Warning("Invalid logLevel, falling back to the default", "logLevel", logLevel, "default", defaultLevel)
+			return
+		}
+		std.mu.Lock()
+		std.level = level
+		std.mu.Unlock()
+	}
+}
+
+// Debug logs a message with debug severity; args are key/value pairs added to the message
+func Debug(msg string, args ...interface{}) {
+	std.log(DebugLevel, msg, args...)
+}
+
+// Info logs a message with info severity; args are key/value pairs added to the message
+func Info(msg string, args ...interface{}) {
+	std.log(InfoLevel, msg, args...)
+}
+
+// Warning logs a message with warning severity; args are key/value pairs added to the message
+func Warning(msg string, args ...interface{}) {
+	std.log(WarningLevel, msg, args...)
+}
+
+// Error logs a message with error severity; args are key/value pairs added to the message
+func Error(msg string, args ...interface{}) {
+	std.log(ErrorLevel, msg, args...)
+}
+
+func (l *logger) log(level Level, msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if level > l.level {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s] %s", time.Now().UTC().Format(logTimestampFmt), level, msg)
+	writeFields(&b, l.context)
+	writeFields(&b, args)
+	b.WriteByte('\n')
+
+	l.write([]byte(b.String()))
+}
+
+// write appends a line to the log file. The file is reopened for every message so that a rotated file is
+// never written to, and each line is written with a single call so that concurrent invocations don't interleave.
+func (l *logger) write(line []byte) {
+	if l.logFile != "" {
+		f, err := os.OpenFile(l.logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, logFilePermission)
+		if err == nil {
+			_, err = f.Write(line)
+			f.Close()
+		}
+		if err == nil {
+			return
+		}
+		fmt.Fprintf(l.stderr, "failed to write to log file %s: %v\n", l.logFile, err)
+	}
+	_, _ = l.stderr.Write(line)
+}
+
+func writeFields(b *strings.Builder, fields []interface{}) {
+	for i := 0; i < len(fields); i += 2 {
+		key := fields[i]
+		if i+1 >= len(fields) {
+			fmt.Fprintf(b, " %v=<missing>", key)
+			break
+		}
+		switch value := fields[i+1].(type) {
+		case string:
+			fmt.Fprintf(b, " %v=%q", key, value)
+		case error:
+			fmt.Fprintf(b, " %v=%q", key, value.Error())
+		default:
+			fmt.Fprintf(b, " %v=%v", key, value)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Logging", func() {
+	var (
+		tmpDir string
+		stderr *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("/tmp", "sriovplugin-testfiles-")
+		Expect(err).NotTo(HaveOccurred())
+		stderr = &bytes.Buffer{}
+		std.stderr = stderr
+	})
+
+	AfterEach(func() {
+		std.stderr = os.Stderr
+		Init("", "", "", "", "")
+		os.RemoveAll(tmpDir)
+	})
+
+	Context("Checking ParseLevel function", func() {
+		It("Parses known levels case insensitively", func() {
+			Expect(ParseLevel("DEBUG")).To(Equal(DebugLevel))
+			Expect(ParseLevel("warn")).To(Equal(WarningLevel))
+			Expect(ParseLevel("verbose")).To(Equal(InvalidLevel))
+		})
+	})
+
+	Context("Checking log output", func() {
+		It("Appends messages with the invocation context to the log file", func() {
+			logFile := filepath.Join(tmpDir, "sriov.log")
+			Init("debug", logFile, "cid", "/var/run/netns/ns1", "net1")
+
+			Debug("Setting VF vlan", "pf", "enp175s0f1", "vf", 0, "vlan", 100)
+			Info("Done")
+
+			data, err := os.ReadFile(logFile)
+			Expect(err).NotTo(HaveOccurred())
+			lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+			Expect(lines).To(HaveLen(2))
+			Expect(string(lines[0])).To(ContainSubstring(`[debug] Setting VF vlan containerID="cid" netns="/var/run/netns/ns1" ifname="net1" pf="enp175s0f1" vf=0 vlan=100`))
+			Expect(string(lines[1])).To(ContainSubstring("[info] Done"))
+			Expect(stderr.Len()).To(BeZero())
+		})
+
+		It("Drops messages more verbose than the configured level", func() {
+			Init("error", "", "cid", "", "net1")
+
+			Info("not logged")
+			Error("logged")
+
+			Expect(stderr.String()).NotTo(ContainSubstring("not logged"))
+			Expect(stderr.String()).To(ContainSubstring("[error] logged"))
+		})
+
+		It("Falls back to stderr when the log file can't be opened", func() {
+			Init("info", filepath.Join(tmpDir, "missing", "sriov.log"), "cid", "", "net1")
+
+			Info("still logged")
+
+			Expect(stderr.String()).To(ContainSubstring("failed to write to log file"))
+			Expect(stderr.String()).To(ContainSubstring("[info] still logged"))
+		})
+
+		It("Falls back to the default level on an invalid logLevel", func() {
+			Init("verbose", "", "cid", "", "net1")
+
+			Debug("not logged")
+
+			Expect(stderr.String()).To(ContainSubstring(`Invalid logLevel, falling back to the default`))
+			Expect(stderr.String()).NotTo(ContainSubstring("not logged"))
+		})
+	})
+})
@@ -0,0 +1,65 @@
+package sriov
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
+)
+
+// Journal records the steps of an attachment as they succeed, so that a failure part way
+// through can undo exactly the steps that were applied, in reverse order
+type Journal struct {
+	steps []journalStep
+}
+
+type journalStep struct {
+	name string
+	undo func() error
+}
+
+// NewJournal returns an empty Journal
+func NewJournal() *Journal {
+	return &Journal{}
+}
+
+// Record adds a step that completed successfully along with the function that undoes it.
+// Recording on a nil Journal is a no-op.
+func (j *Journal) Record(name string, undo func() error) {
+	if j == nil {
+		return
+	}
+	j.steps = append(j.steps, journalStep{name: name, undo: undo})
+}
+
+// Steps returns the names of the recorded steps in the order they were applied
+func (j *Journal) Steps() []string {
+	if j == nil {
+		return nil
+	}
+	names := make([]string, 0, len(j.steps))
+	for _, step := range j.steps {
+		names = append(names, step.name)
+	}
+	return names
+}
+
+// Rollback undoes the recorded steps in reverse order and empties the journal.
+// Every step is attempted even if undoing a later one failed; the errors are combined.
+func (j *Journal) Rollback() error {
+	if j == nil {
+		return nil
+	}
+
+	var errs []error
+	for i := len(j.steps) - 1; i >= 0; i-- {
+		step := j.steps[i]
+		logging.Debug("Undoing step", "step", step.name)
+		if err := step.undo(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to undo %s: %v", step.name, err))
+		}
+	}
+	j.steps = nil
+
+	return errors.Join(errs...)
+}
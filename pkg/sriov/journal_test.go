@@ -0,0 +1,84 @@
+package sriov
+
+import (
+	"errors"
+	"net"
+
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
+	mocks_utils "github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils/mocks"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/vishvananda/netlink"
+)
+
+var _ = Describe("Journal", func() {
+	Context("Checking Rollback function", func() {
+		It("Undoes every step in reverse order even if one fails", func() {
+			var undone []string
+			journal := NewJournal()
+			journal.Record("vlan", func() error {
+				undone = append(undone, "vlan")
+				return nil
+			})
+			journal.Record("mac", func() error {
+				undone = append(undone, "mac")
+				return errors.New("device or resource busy")
+			})
+			journal.Record("trust", func() error {
+				undone = append(undone, "trust")
+				return nil
+			})
+
+			err := journal.Rollback()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to undo mac: device or resource busy"))
+			Expect(undone).To(Equal([]string{"trust", "mac", "vlan"}))
+			Expect(journal.Steps()).To(BeEmpty())
+		})
+		It("Ignores a nil journal", func() {
+			var journal *Journal
+			journal.Record("vlan", func() error { return nil })
+			Expect(journal.Rollback()).To(Succeed())
+		})
+	})
+
+	Context("Checking ApplyVFConfig function with a journal", func() {
+		It("Records only the steps that were applied before a failure", func() {
+			vlan := 100
+			netconf := &sriovtypes.NetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				Vlan:     &vlan,
+				SpoofChk: "off",
+				Trust:    "on",
+				OrigVfState: sriovtypes.VfState{
+					Vlan:     5,
+					SpoofChk: true,
+				},
+			}
+			fakeMac, err := net.ParseMAC("6e:16:06:0e:b7:e9")
+			Expect(err).NotTo(HaveOccurred())
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", HardwareAddr: fakeMac}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfVlan", fakeLink, 0, 100).Return(nil)
+			mocked.On("LinkSetVfSpoofchk", fakeLink, 0, false).Return(nil)
+			mocked.On("LinkSetVfTrust", fakeLink, 0, true).Return(errors.New("operation not supported"))
+
+			journal := NewJournal()
+			sm := sriovManager{nLink: mocked}
+			err = sm.ApplyVFConfig(netconf, journal)
+			Expect(err).To(HaveOccurred())
+			Expect(journal.Steps()).To(Equal([]string{"vlan", "spoofchk"}))
+
+			mocked.On("LinkSetVfSpoofchk", fakeLink, 0, true).Return(nil)
+			mocked.On("LinkSetVfVlan", fakeLink, 0, 5).Return(nil)
+			Expect(journal.Rollback()).To(Succeed())
+			mocked.AssertExpectations(GinkgoT())
+			mocked.AssertNotCalled(GinkgoT(), "LinkSetVfTrust", fakeLink, 0, false)
+		})
+	})
+})
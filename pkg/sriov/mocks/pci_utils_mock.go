@@ -2,7 +2,10 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	types "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // PciUtils is an autogenerated mock type for the pciUtils type
 type PciUtils struct {
@@ -23,6 +26,27 @@ func (_m *PciUtils) EnableArpAndNdiscNotify(ifName string) error {
 	return r0
 }
 
+// GetLinkPciAddress provides a mock function with given fields: ifName
+func (_m *PciUtils) GetLinkPciAddress(ifName string) (string, error) {
+	ret := _m.Called(ifName)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(ifName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(ifName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetPciAddress provides a mock function with given fields: ifName, vf
 func (_m *PciUtils) GetPciAddress(ifName string, vf int) (string, error) {
 	ret := _m.Called(ifName, vf)
@@ -44,6 +68,69 @@ func (_m *PciUtils) GetPciAddress(ifName string, vf int) (string, error) {
 	return r0, r1
 }
 
+// GetPfPciAddress provides a mock function with given fields: pfName
+func (_m *PciUtils) GetPfPciAddress(pfName string) (string, error) {
+	ret := _m.Called(pfName)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(pfName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pfName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSfLinkName provides a mock function with given fields: auxDev
+func (_m *PciUtils) GetSfLinkName(auxDev string) (string, error) {
+	ret := _m.Called(auxDev)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(auxDev)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(auxDev)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSfRepresentor provides a mock function with given fields: pfName, sfNum
+func (_m *PciUtils) GetSfRepresentor(pfName string, sfNum int) (string, error) {
+	ret := _m.Called(pfName, sfNum)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, int) string); ok {
+		r0 = rf(pfName, sfNum)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(pfName, sfNum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetSriovNumVfs provides a mock function with given fields: ifName
 func (_m *PciUtils) GetSriovNumVfs(ifName string) (int, error) {
 	ret := _m.Called(ifName)
@@ -88,6 +175,85 @@ func (_m *PciUtils) GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, e
 	return r0, r1
 }
 
+// GetVdpaDevice provides a mock function with given fields: pciAddr
+func (_m *PciUtils) GetVdpaDevice(pciAddr string) (*types.VdpaDevice, error) {
+	ret := _m.Called(pciAddr)
+
+	var r0 *types.VdpaDevice
+	if rf, ok := ret.Get(0).(func(string) *types.VdpaDevice); ok {
+		r0 = rf(pciAddr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.VdpaDevice)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVfRepresentor provides a mock function with given fields: pfName, vfID
+func (_m *PciUtils) GetVfRepresentor(pfName string, vfID int) (string, error) {
+	ret := _m.Called(pfName, vfID)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, int) string); ok {
+		r0 = rf(pfName, vfID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(pfName, vfID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HasDpdkDriver provides a mock function with given fields: pciAddr
+func (_m *PciUtils) HasDpdkDriver(pciAddr string) (bool, error) {
+	ret := _m.Called(pciAddr)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetIfSysctl provides a mock function with given fields: ipVersion, ifName, param, value
+func (_m *PciUtils) SetIfSysctl(ipVersion string, ifName string, param string, value string) error {
+	ret := _m.Called(ipVersion, ifName, param, value)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, string) error); ok {
+		r0 = rf(ipVersion, ifName, param, value)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewPciUtils interface {
 	mock.TestingT
 	Cleanup(func())
@@ -1,9 +1,20 @@
 package sriov
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	cnitypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/plugins/pkg/ns"
 
+	srioverrors "github.com/k8snetworkplumbingwg/sriov-cni/pkg/errors"
+	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/logging"
 	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
 	"github.com/vishvananda/netlink"
@@ -14,6 +25,14 @@ type pciUtils interface {
 	GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error)
 	GetPciAddress(ifName string, vf int) (string, error)
 	EnableArpAndNdiscNotify(ifName string) error
+	HasDpdkDriver(pciAddr string) (bool, error)
+	GetLinkPciAddress(ifName string) (string, error)
+	GetPfPciAddress(pfName string) (string, error)
+	GetVfRepresentor(pfName string, vfID int) (string, error)
+	GetSfRepresentor(pfName string, sfNum int) (string, error)
+	GetSfLinkName(auxDev string) (string, error)
+	GetVdpaDevice(pciAddr string) (*sriovtypes.VdpaDevice, error)
+	SetIfSysctl(ipVersion, ifName, param, value string) error
 }
 
 type pciUtilsImpl struct{}
@@ -34,83 +53,197 @@ func (p *pciUtilsImpl) EnableArpAndNdiscNotify(ifName string) error {
 	return utils.EnableArpAndNdiscNotify(ifName)
 }
 
+func (p *pciUtilsImpl) HasDpdkDriver(pciAddr string) (bool, error) {
+	return utils.HasDpdkDriver(pciAddr)
+}
+
+func (p *pciUtilsImpl) GetLinkPciAddress(ifName string) (string, error) {
+	return utils.GetLinkPciAddress(ifName)
+}
+
+func (p *pciUtilsImpl) GetPfPciAddress(pfName string) (string, error) {
+	return utils.GetPfPciAddress(pfName)
+}
+
+func (p *pciUtilsImpl) GetVfRepresentor(pfName string, vfID int) (string, error) {
+	return utils.GetVfRepresentor(pfName, vfID)
+}
+
+func (p *pciUtilsImpl) GetSfRepresentor(pfName string, sfNum int) (string, error) {
+	return utils.GetSfRepresentor(pfName, sfNum)
+}
+
+func (p *pciUtilsImpl) GetSfLinkName(auxDev string) (string, error) {
+	return utils.GetSfLinkName(auxDev)
+}
+
+func (p *pciUtilsImpl) GetVdpaDevice(pciAddr string) (*sriovtypes.VdpaDevice, error) {
+	return utils.GetVdpaDevice(pciAddr)
+}
+
+func (p *pciUtilsImpl) SetIfSysctl(ipVersion, ifName, param, value string) error {
+	return utils.SetIfSysctl(ipVersion, ifName, param, value)
+}
+
 // Manager provides interface invoke sriov nic related operations
 type Manager interface {
-	SetupVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error
+	SetupVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, journal *Journal) error
 	ReleaseVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error
 	ResetVFConfig(conf *sriovtypes.NetConf) error
-	ApplyVFConfig(conf *sriovtypes.NetConf) error
+	ResetVFToDefault(conf *sriovtypes.NetConf) error
+	ReleaseVFByPCI(conf *sriovtypes.NetConf, netns ns.NetNS) error
+	RestoreVFHostState(conf *sriovtypes.NetConf) error
+	DetectSwitchdev(conf *sriovtypes.NetConf) error
+	ApplyVFConfig(conf *sriovtypes.NetConf, journal *Journal) error
 	FillOriginalVfInfo(conf *sriovtypes.NetConf) error
+	CheckVFConfig(conf *sriovtypes.NetConf) error
+	CheckVF(podifName string, expectedMAC string, netns ns.NetNS) error
 }
 
 type sriovManager struct {
-	nLink utils.NetlinkManager
-	utils pciUtils
+	nLink   utils.NetlinkManager
+	sysfs   utils.SriovSysfsManager
+	ethtool utils.EthtoolManager
+	devlink utils.DevlinkManager
+	utils   pciUtils
 }
 
 // NewSriovManager returns an instance of SriovManager
 func NewSriovManager() Manager {
 	return &sriovManager{
-		nLink: &utils.MyNetlink{},
-		utils: &pciUtilsImpl{},
+		nLink:   &utils.MyNetlink{},
+		sysfs:   &utils.MySysfs{},
+		ethtool: &utils.MyEthtool{},
+		devlink: &utils.MyDevlink{},
+		utils:   &pciUtilsImpl{},
 	}
 }
 
-// SetupVF sets up a VF in Pod netns
-func (s *sriovManager) SetupVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS) error {
+// SetupVF sets up a VF in Pod netns, recording each completed step in journal
+func (s *sriovManager) SetupVF(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, journal *Journal) error {
 	linkName := conf.OrigVfState.HostIFName
 
+	logging.Debug("Setting up VF in pod netns", vfLogFields(conf, "hostIFName", linkName, "podIFName", podifName)...)
 	linkObj, err := s.nLink.LinkByName(linkName)
 	if err != nil {
 		return fmt.Errorf("error getting VF netdevice with name %s", linkName)
 	}
 
+	// Save the original link attributes restored on release, and check the MTU before touching the VF
+	conf.OrigVfState.MTU = linkObj.Attrs().MTU
+	conf.OrigVfState.TxQueueLen = linkObj.Attrs().TxQLen
+	conf.OrigVfState.Promisc = linkObj.Attrs().Promisc != 0
+	conf.OrigVfState.Allmulti = linkObj.Attrs().Allmulti != 0
+	if conf.MTU != nil {
+		if err := s.checkMTU(conf, linkObj); err != nil {
+			return err
+		}
+	}
+
 	// tempName used as intermediary name to avoid name conflicts
 	tempName := fmt.Sprintf("%s%d", "temp_", linkObj.Attrs().Index)
 
 	// 1. Set link down
+	logging.Debug("Setting VF link down", vfLogFields(conf, "link", linkName)...)
 	if err := s.nLink.LinkSetDown(linkObj); err != nil {
 		return fmt.Errorf("failed to down vf device %q: %v", linkName, err)
 	}
 
 	// 2. Set temp name
+	logging.Debug("Renaming VF to temporary name", vfLogFields(conf, "link", linkName, "tempName", tempName)...)
 	if err := s.nLink.LinkSetName(linkObj, tempName); err != nil {
 		return fmt.Errorf("error setting temp IF name %s for %s", tempName, linkName)
 	}
+	journal.Record("rename", func() error {
+		return s.renameLink(tempName, linkName)
+	})
 
 	// Save the original effective MAC address before overriding it
 	conf.OrigVfState.EffectiveMAC = linkObj.Attrs().HardwareAddr.String()
 	// 3. Set MAC address
 	if conf.MAC != "" {
+		logging.Debug("Setting VF effective MAC address", vfLogFields(conf, "link", tempName, "mac", conf.MAC)...)
 		err = utils.SetVFEffectiveMAC(s.nLink, tempName, conf.MAC)
 		if err != nil {
 			return fmt.Errorf("failed to set netlink MAC address to %s: %v", conf.MAC, err)
 		}
+		origEffectiveMAC := conf.OrigVfState.EffectiveMAC
+		journal.Record("effective mac", func() error {
+			return utils.SetVFEffectiveMAC(s.nLink, tempName, origEffectiveMAC)
+		})
 	}
 
 	// 4. Change netns
+	logging.Debug("Moving VF to pod netns", vfLogFields(conf, "link", tempName, "netns", netns.Path())...)
 	if err := s.nLink.LinkSetNsFd(linkObj, int(netns.Fd())); err != nil {
 		return fmt.Errorf("failed to move IF %s to netns: %q", tempName, err)
 	}
+	journal.Record("netns move", func() error {
+		initns, err := ns.GetCurrentNS()
+		if err != nil {
+			return fmt.Errorf("failed to get init netns: %v", err)
+		}
+		defer initns.Close()
+		return netns.Do(func(_ ns.NetNS) error {
+			linkObj, err := s.nLink.LinkByName(tempName)
+			if err != nil {
+				return fmt.Errorf("failed to get netlink device with name %s: %q", tempName, err)
+			}
+			return s.nLink.LinkSetNsFd(linkObj, int(initns.Fd()))
+		})
+	})
 
 	if err := netns.Do(func(_ ns.NetNS) error {
 		// 5. Set Pod IF name
+		logging.Debug("Renaming VF to pod interface name", vfLogFields(conf, "link", tempName, "podIFName", podifName)...)
 		if err := s.nLink.LinkSetName(linkObj, podifName); err != nil {
 			return fmt.Errorf("error setting container interface name %s for %s", linkName, tempName)
 		}
+		journal.Record("pod rename", func() error {
+			return netns.Do(func(_ ns.NetNS) error {
+				return s.renameLink(podifName, tempName)
+			})
+		})
 
 		// 6. Enable IPv4 ARP notify and IPv6 Network Discovery notify
 		// Error is ignored here because enabling this feature is only a performance enhancement.
 		_ = s.utils.EnableArpAndNdiscNotify(podifName)
 
-		// 7. Bring IF up in Pod netns
+		// 7. Set MTU, transmit queue length and flags of the Pod IF
+		if err := s.setPodLinkAttrs(conf, linkObj, podifName, netns, journal); err != nil {
+			return err
+		}
+
+		// 8. Set offloads, ring sizes and channel counts of the Pod IF
+		if conf.Ethtool != nil {
+			if err := s.setPodEthtool(conf, podifName, netns, journal); err != nil {
+				return err
+			}
+		}
+
+		// 9. Set the per-interface sysctls of the Pod IF
+		if err := s.setPodSysctls(conf, podifName); err != nil {
+			return err
+		}
+
+		// 10. Bring IF up in Pod netns
+		logging.Debug("Setting VF link up", vfLogFields(conf, "link", podifName)...)
 		if err := s.nLink.LinkSetUp(linkObj); err != nil {
 			return fmt.Errorf("error bringing interface up in container ns: %q", err)
 		}
+		journal.Record("link up", func() error {
+			return netns.Do(func(_ ns.NetNS) error {
+				linkObj, err := s.nLink.LinkByName(podifName)
+				if err != nil {
+					return fmt.Errorf("failed to get netlink device with name %s: %q", podifName, err)
+				}
+				return s.nLink.LinkSetDown(linkObj)
+			})
+		})
 
 		return nil
 	}); err != nil {
-		return fmt.Errorf("error setting up interface in container namespace: %q", err)
+		return fmt.Errorf("error setting up interface in container namespace: %w", err)
 	}
 	conf.ContIFNames = podifName
 
@@ -128,6 +261,7 @@ func (s *sriovManager) ReleaseVF(conf *sriovtypes.NetConf, podifName string, net
 		return fmt.Errorf("number of interface names mismatch ContIFNames: %d HostIFNames: %d", len(conf.ContIFNames), len(conf.OrigVfState.HostIFName))
 	}
 
+	logging.Debug("Releasing VF from pod netns", vfLogFields(conf, "podIFName", podifName, "hostIFName", conf.OrigVfState.HostIFName)...)
 	return netns.Do(func(_ ns.NetNS) error {
 		// get VF device
 		linkObj, err := s.nLink.LinkByName(podifName)
@@ -136,11 +270,13 @@ func (s *sriovManager) ReleaseVF(conf *sriovtypes.NetConf, podifName string, net
 		}
 
 		// shutdown VF device
+		logging.Debug("Setting VF link down", vfLogFields(conf, "link", podifName)...)
 		if err = s.nLink.LinkSetDown(linkObj); err != nil {
 			return fmt.Errorf("failed to set link %s down: %q", podifName, err)
 		}
 
 		// rename VF device
+		logging.Debug("Renaming VF to host interface name", vfLogFields(conf, "link", podifName, "hostIFName", conf.OrigVfState.HostIFName)...)
 		err = s.nLink.LinkSetName(linkObj, conf.OrigVfState.HostIFName)
 		if err != nil {
 			return fmt.Errorf("failed to rename link %s to host name %s: %q", podifName, conf.OrigVfState.HostIFName, err)
@@ -148,198 +284,1609 @@ func (s *sriovManager) ReleaseVF(conf *sriovtypes.NetConf, podifName string, net
 
 		if conf.MAC != "" {
 			// reset effective MAC address
+			logging.Debug("Restoring VF effective MAC address", vfLogFields(conf, "link", conf.OrigVfState.HostIFName, "mac", conf.OrigVfState.EffectiveMAC)...)
 			err = utils.SetVFEffectiveMAC(s.nLink, conf.OrigVfState.HostIFName, conf.OrigVfState.EffectiveMAC)
 			if err != nil {
 				return fmt.Errorf("failed to restore original effective netlink MAC address %s: %v", conf.OrigVfState.EffectiveMAC, err)
 			}
 		}
 
+		// The attributes are restored best-effort, the VF must be moved back to the init netns regardless:
+		// DEL isn't retried once it's released, so a VF left in the Pod netns would be lost
+		var errs []error
+
+		// reset MTU, transmit queue length and flags
+		if err = s.restoreLinkAttrs(conf, linkObj); err != nil {
+			logging.Error("Failed to restore VF link attributes", vfLogFields(conf, "link", conf.OrigVfState.HostIFName, "error", err)...)
+			errs = append(errs, err)
+		}
+
+		// reset offloads, ring sizes and channel counts
+		if err = s.restoreEthtool(conf, conf.OrigVfState.HostIFName); err != nil {
+			logging.Error("Failed to restore VF ethtool settings", vfLogFields(conf, "link", conf.OrigVfState.HostIFName, "error", err)...)
+			errs = append(errs, err)
+		}
+
 		// move VF device to init netns
+		logging.Debug("Moving VF to init netns", vfLogFields(conf, "link", conf.OrigVfState.HostIFName)...)
 		if err = s.nLink.LinkSetNsFd(linkObj, int(initns.Fd())); err != nil {
-			return fmt.Errorf("failed to move interface %s to init netns: %v", conf.OrigVfState.HostIFName, err)
+			errs = append(errs, fmt.Errorf("failed to move interface %s to init netns: %v", conf.OrigVfState.HostIFName, err))
 		}
 
-		return nil
+		return errors.Join(errs...)
 	})
 }
 
-func getVfInfo(link netlink.Link, id int) *netlink.VfInfo {
-	attrs := link.Attrs()
-	for _, vf := range attrs.Vfs {
-		if vf.ID == id {
-			return &vf
+// ReleaseVFByPCI moves the netdev of the VF of conf from the Pod netns back to the init netns, finding it
+// by its pci address rather than by name. It is used when the interface names recorded at ADD are unknown.
+// The netdev gets its original host name when known, or a name derived from its pci address otherwise.
+func (s *sriovManager) ReleaseVFByPCI(conf *sriovtypes.NetConf, netns ns.NetNS) error {
+	initns, err := ns.GetCurrentNS()
+	if err != nil {
+		return fmt.Errorf("failed to get init netns: %v", err)
+	}
+
+	hostIFName := conf.OrigVfState.HostIFName
+	if hostIFName == "" && conf.SFNum != nil {
+		// <pf>s<sfnum> is the name SF netdevs get from udev, shortened when it doesn't fit IFNAMSIZ
+		if hostIFName = fmt.Sprintf("%ss%d", conf.Master, *conf.SFNum); len(hostIFName) > 15 {
+			hostIFName = fmt.Sprintf("sriovsf%d", *conf.SFNum)
 		}
+	} else if hostIFName == "" {
+		hostIFName = "sriov" + strings.NewReplacer(":", "", ".", "").Replace(conf.DeviceID)
 	}
-	return nil
+
+	logging.Debug("Releasing VF from pod netns by pci address", vfLogFields(conf, "hostIFName", hostIFName)...)
+	return netns.Do(func(_ ns.NetNS) error {
+		links, err := s.nLink.LinkList()
+		if err != nil {
+			return fmt.Errorf("failed to list links: %v", err)
+		}
+
+		// virtio_net reports the vDPA device as bus info rather than the VF
+		busInfo := conf.DeviceID
+		if conf.VdpaDevice != nil {
+			busInfo = conf.VdpaDevice.Name
+		}
+
+		var linkObj netlink.Link
+		for _, link := range links {
+			pciAddr, err := s.utils.GetLinkPciAddress(link.Attrs().Name)
+			if err == nil && pciAddr == busInfo {
+				linkObj = link
+				break
+			}
+		}
+		if linkObj == nil {
+			return fmt.Errorf("failed to find netdev of VF %s in netns", conf.DeviceID)
+		}
+		podifName := linkObj.Attrs().Name
+
+		logging.Debug("Setting VF link down", vfLogFields(conf, "link", podifName)...)
+		if err = s.nLink.LinkSetDown(linkObj); err != nil {
+			return fmt.Errorf("failed to set link %s down: %q", podifName, err)
+		}
+
+		logging.Debug("Renaming VF to host interface name", vfLogFields(conf, "link", podifName, "hostIFName", hostIFName)...)
+		if err = s.nLink.LinkSetName(linkObj, hostIFName); err != nil {
+			return fmt.Errorf("failed to rename link %s to host name %s: %q", podifName, hostIFName, err)
+		}
+
+		logging.Debug("Moving VF to init netns", vfLogFields(conf, "link", hostIFName)...)
+		if err = s.nLink.LinkSetNsFd(linkObj, int(initns.Fd())); err != nil {
+			return fmt.Errorf("failed to move interface %s to init netns: %v", hostIFName, err)
+		}
+
+		return nil
+	})
 }
 
-// ApplyVFConfig configure a VF with parameters given in NetConf
-func (s *sriovManager) ApplyVFConfig(conf *sriovtypes.NetConf) error {
-	pfLink, err := s.nLink.LinkByName(conf.Master)
-	if err != nil {
-		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+// RestoreVFHostState restores the original host name and effective MAC of the VF of conf once its netdev is
+// back in the init netns without ReleaseVF, as happens when the Pod netns is destroyed before DEL. The kernel
+// then moves the netdev back on its own, possibly under another name. Nothing is done if it isn't back yet.
+func (s *sriovManager) RestoreVFHostState(conf *sriovtypes.NetConf) error {
+	names, err := s.hostLinkNames(conf)
+	if err != nil || len(names) == 0 {
+		logging.Debug("VF netdev is not in the init netns, nothing to restore", vfLogFields(conf)...)
+		return nil
 	}
-	// 1. Set vlan
-	if conf.Vlan == nil {
-		vlan := new(int)
-		*vlan = 0
-		conf.Vlan = vlan
+
+	hostIFName := conf.OrigVfState.HostIFName
+	if hostIFName != "" && names[0] != hostIFName {
+		linkObj, err := s.nLink.LinkByName(names[0])
+		if err != nil {
+			return fmt.Errorf("failed to get netlink device with name %s: %q", names[0], err)
+		}
+
+		logging.Debug("Setting VF link down", vfLogFields(conf, "link", names[0])...)
+		if err = s.nLink.LinkSetDown(linkObj); err != nil {
+			return fmt.Errorf("failed to set link %s down: %q", names[0], err)
+		}
+
+		logging.Debug("Renaming VF to host interface name", vfLogFields(conf, "link", names[0], "hostIFName", hostIFName)...)
+		if err = s.nLink.LinkSetName(linkObj, hostIFName); err != nil {
+			return fmt.Errorf("failed to rename link %s to host name %s: %q", names[0], hostIFName, err)
+		}
+	} else if hostIFName == "" {
+		hostIFName = names[0]
 	}
-	// set vlan qos if present in the config
-	if conf.VlanQoS != nil {
-		if err = s.nLink.LinkSetVfVlanQos(pfLink, conf.VFID, *conf.Vlan, *conf.VlanQoS); err != nil {
-			return fmt.Errorf("failed to set vf %d vlan configuration: %v", conf.VFID, err)
+
+	if conf.MAC != "" && conf.OrigVfState.EffectiveMAC != "" {
+		logging.Debug("Restoring VF effective MAC address", vfLogFields(conf, "link", hostIFName, "mac", conf.OrigVfState.EffectiveMAC)...)
+		if err = utils.SetVFEffectiveMAC(s.nLink, hostIFName, conf.OrigVfState.EffectiveMAC); err != nil {
+			return fmt.Errorf("failed to restore original effective netlink MAC address %s: %v", conf.OrigVfState.EffectiveMAC, err)
 		}
-	} else {
-		// set vlan id field only
-		if err = s.nLink.LinkSetVfVlan(pfLink, conf.VFID, *conf.Vlan); err != nil {
-			return fmt.Errorf("failed to set vf %d vlan: %v", conf.VFID, err)
+	}
+
+	var errs []error
+	if hasLinkAttrs(conf) {
+		linkObj, err := s.nLink.LinkByName(hostIFName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get netlink device with name %s: %q", hostIFName, err))
+		} else if err = s.restoreLinkAttrs(conf, linkObj); err != nil {
+			errs = append(errs, err)
 		}
 	}
+	if err = s.restoreEthtool(conf, hostIFName); err != nil {
+		errs = append(errs, err)
+	}
 
-	// 2. Set mac address
-	if conf.MAC != "" {
-		// when we restore the original hardware mac address we may get a device or resource busy. so we introduce retry
-		if err := utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.MAC); err != nil {
-			return fmt.Errorf("failed to set MAC address to %s: %v", conf.MAC, err)
+	return errors.Join(errs...)
+}
+
+// hostLinkNames returns the names of the netdevs of the VF or SF of conf in the init netns
+func (s *sriovManager) hostLinkNames(conf *sriovtypes.NetConf) ([]string, error) {
+	if conf.VdpaDevice != nil {
+		vdpaDev, err := s.utils.GetVdpaDevice(conf.DeviceID)
+		if err != nil || vdpaDev == nil || vdpaDev.NetDev == "" {
+			return nil, err
 		}
+		return []string{vdpaDev.NetDev}, nil
 	}
+	if conf.SFNum == nil {
+		return s.utils.GetVFLinkNamesFromVFID(conf.Master, conf.VFID)
+	}
+	name, err := s.utils.GetSfLinkName(conf.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	return []string{name}, nil
+}
 
-	// 3. Set min/max tx link rate. 0 means no rate limiting. Support depends on NICs and driver.
-	var minTxRate, maxTxRate int
-	rateConfigured := false
-	if conf.MinTxRate != nil {
-		minTxRate = *conf.MinTxRate
-		rateConfigured = true
+// hasLinkAttrs tells whether the MTU, transmit queue length or flags of the Pod IF are configured in conf
+func hasLinkAttrs(conf *sriovtypes.NetConf) bool {
+	return conf.MTU != nil || conf.TxQueueLen != nil || conf.Promisc != "" || conf.Allmulti != ""
+}
+
+// checkMTU verifies that the MTU configured in conf fits both the PF and the maximum MTU of the VF netdev
+func (s *sriovManager) checkMTU(conf *sriovtypes.NetConf, linkObj netlink.Link) error {
+	pfLink, err := s.nLink.LinkByName(conf.Master)
+	if err != nil {
+		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+	}
+	if *conf.MTU > pfLink.Attrs().MTU {
+		return srioverrors.InvalidConfig("mtu %d of vf %d exceeds the mtu %d of PF %s", *conf.MTU, conf.VFID, pfLink.Attrs().MTU, conf.Master)
 	}
 
-	if conf.MaxTxRate != nil {
-		maxTxRate = *conf.MaxTxRate
-		rateConfigured = true
+	maxMTU, err := s.nLink.LinkGetMaxMTU(linkObj)
+	if err != nil {
+		return fmt.Errorf("failed to get maximum mtu of vf %d: %v", conf.VFID, err)
 	}
+	if maxMTU != 0 && *conf.MTU > maxMTU {
+		return srioverrors.InvalidConfig("mtu %d of vf %d exceeds its maximum mtu %d", *conf.MTU, conf.VFID, maxMTU)
+	}
+	return nil
+}
 
-	if rateConfigured {
-		if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, minTxRate, maxTxRate); err != nil {
-			return fmt.Errorf("failed to set vf %d min_tx_rate to %d Mbps: max_tx_rate to %d Mbps: %v",
-				conf.VFID, minTxRate, maxTxRate, err)
+// setPromisc turns the promiscuous mode of linkObj on or off
+func (s *sriovManager) setPromisc(linkObj netlink.Link, on bool) error {
+	if on {
+		return s.nLink.LinkSetPromiscOn(linkObj)
+	}
+	return s.nLink.LinkSetPromiscOff(linkObj)
+}
+
+// setAllmulti turns the reception of all multicast packets of linkObj on or off
+func (s *sriovManager) setAllmulti(linkObj netlink.Link, on bool) error {
+	if on {
+		return s.nLink.LinkSetAllmulticastOn(linkObj)
+	}
+	return s.nLink.LinkSetAllmulticastOff(linkObj)
+}
+
+// setPodLinkAttrs sets the MTU, transmit queue length and flags configured in conf on the Pod IF linkObj,
+// recording each completed step in journal. It runs in the Pod netns.
+func (s *sriovManager) setPodLinkAttrs(conf *sriovtypes.NetConf, linkObj netlink.Link, podifName string, netns ns.NetNS, journal *Journal) error {
+	// undo runs op on the Pod IF in the Pod netns, as the journal is rolled back from the init netns
+	undo := func(op func(netlink.Link) error) func() error {
+		return func() error {
+			return netns.Do(func(_ ns.NetNS) error {
+				linkObj, err := s.nLink.LinkByName(podifName)
+				if err != nil {
+					return fmt.Errorf("failed to get netlink device with name %s: %q", podifName, err)
+				}
+				return op(linkObj)
+			})
 		}
 	}
 
-	// 4. Set spoofchk flag
-	if conf.SpoofChk != "" {
-		spoofChk := false
-		if conf.SpoofChk == "on" {
-			spoofChk = true
+	if conf.MTU != nil {
+		logging.Debug("Setting VF mtu", vfLogFields(conf, "link", podifName, "mtu", *conf.MTU)...)
+		if err := s.nLink.LinkSetMTU(linkObj, *conf.MTU); err != nil {
+			return fmt.Errorf("failed to set mtu %d on %s: %v", *conf.MTU, podifName, err)
 		}
-		if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, spoofChk); err != nil {
-			return fmt.Errorf("failed to set vf %d spoofchk flag to %s: %v", conf.VFID, conf.SpoofChk, err)
+		journal.Record("mtu", undo(func(linkObj netlink.Link) error {
+			return s.nLink.LinkSetMTU(linkObj, conf.OrigVfState.MTU)
+		}))
+	}
+
+	if conf.TxQueueLen != nil {
+		logging.Debug("Setting VF transmit queue length", vfLogFields(conf, "link", podifName, "txQueueLen", *conf.TxQueueLen)...)
+		if err := s.nLink.LinkSetTxQLen(linkObj, *conf.TxQueueLen); err != nil {
+			return fmt.Errorf("failed to set transmit queue length %d on %s: %v", *conf.TxQueueLen, podifName, err)
 		}
+		journal.Record("txqueuelen", undo(func(linkObj netlink.Link) error {
+			return s.nLink.LinkSetTxQLen(linkObj, conf.OrigVfState.TxQueueLen)
+		}))
 	}
 
-	// 5. Set trust flag
-	if conf.Trust != "" {
-		trust := false
-		if conf.Trust == "on" {
-			trust = true
+	if conf.Promisc != "" {
+		logging.Debug("Setting VF promiscuous mode", vfLogFields(conf, "link", podifName, "promisc", conf.Promisc)...)
+		if err := s.setPromisc(linkObj, conf.Promisc == "on"); err != nil {
+			return fmt.Errorf("failed to set promisc %s on %s: %v", conf.Promisc, podifName, err)
 		}
-		if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, trust); err != nil {
-			return fmt.Errorf("failed to set vf %d trust flag to %s: %v", conf.VFID, conf.Trust, err)
+		journal.Record("promisc", undo(func(linkObj netlink.Link) error {
+			return s.setPromisc(linkObj, conf.OrigVfState.Promisc)
+		}))
+	}
+
+	if conf.Allmulti != "" {
+		logging.Debug("Setting VF allmulticast mode", vfLogFields(conf, "link", podifName, "allmulti", conf.Allmulti)...)
+		if err := s.setAllmulti(linkObj, conf.Allmulti == "on"); err != nil {
+			return fmt.Errorf("failed to set allmulti %s on %s: %v", conf.Allmulti, podifName, err)
 		}
+		journal.Record("allmulti", undo(func(linkObj netlink.Link) error {
+			return s.setAllmulti(linkObj, conf.OrigVfState.Allmulti)
+		}))
 	}
 
-	// 6. Set link state
-	if conf.LinkState != "" {
-		var state uint32
-		switch conf.LinkState {
-		case "auto":
-			state = netlink.VF_LINK_STATE_AUTO
-		case "enable":
-			state = netlink.VF_LINK_STATE_ENABLE
-		case "disable":
-			state = netlink.VF_LINK_STATE_DISABLE
-		default:
-			// the value should have been validated earlier, return error if we somehow got here
-			return fmt.Errorf("unknown link state %s when setting it for vf %d: %v", conf.LinkState, conf.VFID, err)
+	return nil
+}
+
+// restoreLinkAttrs restores the original MTU, transmit queue length and flags of the VF netdev linkObj,
+// for those configured in conf. A failure doesn't stop the other attributes from being restored.
+func (s *sriovManager) restoreLinkAttrs(conf *sriovtypes.NetConf, linkObj netlink.Link) error {
+	var errs []error
+	if conf.MTU != nil {
+		logging.Debug("Restoring VF mtu", vfLogFields(conf, "mtu", conf.OrigVfState.MTU)...)
+		if err := s.nLink.LinkSetMTU(linkObj, conf.OrigVfState.MTU); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore original mtu %d: %v", conf.OrigVfState.MTU, err))
 		}
-		if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, state); err != nil {
-			return fmt.Errorf("failed to set vf %d link state to %d: %v", conf.VFID, state, err)
+	}
+	if conf.TxQueueLen != nil {
+		logging.Debug("Restoring VF transmit queue length", vfLogFields(conf, "txQueueLen", conf.OrigVfState.TxQueueLen)...)
+		if err := s.nLink.LinkSetTxQLen(linkObj, conf.OrigVfState.TxQueueLen); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore original transmit queue length %d: %v", conf.OrigVfState.TxQueueLen, err))
+		}
+	}
+	if conf.Promisc != "" {
+		logging.Debug("Restoring VF promiscuous mode", vfLogFields(conf, "promisc", conf.OrigVfState.Promisc)...)
+		if err := s.setPromisc(linkObj, conf.OrigVfState.Promisc); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore original promisc %s: %v", onOff(conf.OrigVfState.Promisc), err))
+		}
+	}
+	if conf.Allmulti != "" {
+		logging.Debug("Restoring VF allmulticast mode", vfLogFields(conf, "allmulti", conf.OrigVfState.Allmulti)...)
+		if err := s.setAllmulti(linkObj, conf.OrigVfState.Allmulti); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore original allmulti %s: %v", onOff(conf.OrigVfState.Allmulti), err))
 		}
 	}
+	return errors.Join(errs...)
+}
 
-	return nil
+// ethtoolError explains the error of an ethtool request setting attr of the VF netdev ifName
+func (s *sriovManager) ethtoolError(err error, ifName, attr string) error {
+	driver, driverErr := s.ethtool.DriverName(ifName)
+	if driverErr != nil {
+		driver = "unknown"
+	}
+	return srioverrors.Ethtool(err, ifName, driver, attr)
 }
 
-// FillOriginalVfInfo fills the original vf info
-func (s *sriovManager) FillOriginalVfInfo(conf *sriovtypes.NetConf) error {
-	pfLink, err := s.nLink.LinkByName(conf.Master)
+// setRing sets the rx and tx ring sizes of ifName set in ring, returning their previous values
+func (s *sriovManager) setRing(ifName string, ring *sriovtypes.EthtoolRing) (*sriovtypes.EthtoolRing, error) {
+	rx, tx, err := s.ethtool.GetRing(ifName)
 	if err != nil {
-		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+		return nil, err
 	}
-	// Save current the VF state before modifying it
-	vfState := getVfInfo(pfLink, conf.VFID)
-	if vfState == nil {
-		return fmt.Errorf("failed to find vf %d", conf.VFID)
+	prev := &sriovtypes.EthtoolRing{}
+	if ring.Rx != nil {
+		prevRx := rx
+		prev.Rx, rx = &prevRx, *ring.Rx
 	}
-	conf.OrigVfState.FillFromVfInfo(vfState)
-
-	return err
+	if ring.Tx != nil {
+		prevTx := tx
+		prev.Tx, tx = &prevTx, *ring.Tx
+	}
+	return prev, s.ethtool.SetRing(ifName, rx, tx)
 }
 
-// ResetVFConfig reset a VF to its original state
-func (s *sriovManager) ResetVFConfig(conf *sriovtypes.NetConf) error {
-	pfLink, err := s.nLink.LinkByName(conf.Master)
+// setChannels sets the channel counts of ifName set in channels, returning their previous values
+func (s *sriovManager) setChannels(ifName string, channels *sriovtypes.EthtoolChannels) (*sriovtypes.EthtoolChannels, error) {
+	current, err := s.ethtool.GetChannels(ifName)
 	if err != nil {
-		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+		return nil, err
+	}
+	prev := &sriovtypes.EthtoolChannels{}
+	if channels.Rx != nil {
+		rx := current.RxCount
+		prev.Rx, current.RxCount = &rx, *channels.Rx
+	}
+	if channels.Tx != nil {
+		tx := current.TxCount
+		prev.Tx, current.TxCount = &tx, *channels.Tx
 	}
+	if channels.Other != nil {
+		other := current.OtherCount
+		prev.Other, current.OtherCount = &other, *channels.Other
+	}
+	if channels.Combined != nil {
+		combined := current.CombinedCount
+		prev.Combined, current.CombinedCount = &combined, *channels.Combined
+	}
+	return prev, s.ethtool.SetChannels(ifName, current)
+}
 
-	// Restore VLAN
-	if conf.Vlan != nil {
-		if conf.VlanQoS != nil {
-			if err = s.nLink.LinkSetVfVlanQos(pfLink, conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS); err != nil {
-				return fmt.Errorf("failed to restore vf %d vlan: %v", conf.VFID, err)
-			}
-		} else if err = s.nLink.LinkSetVfVlan(pfLink, conf.VFID, conf.OrigVfState.Vlan); err != nil {
-			return fmt.Errorf("failed to restore vf %d vlan: %v", conf.VFID, err)
+// setPodEthtool applies the ethtool settings of conf to the Pod IF podifName, saving their original values in
+// conf.OrigVfState.Ethtool and recording each completed step in journal. It runs in the Pod netns.
+func (s *sriovManager) setPodEthtool(conf *sriovtypes.NetConf, podifName string, netns ns.NetNS, journal *Journal) error {
+	orig := &sriovtypes.EthtoolConf{}
+	conf.OrigVfState.Ethtool = orig
+
+	// undo runs op in the Pod netns, as the journal is rolled back from the init netns
+	undo := func(op func() error) func() error {
+		return func() error {
+			return netns.Do(func(_ ns.NetNS) error {
+				return op()
+			})
 		}
 	}
 
-	// Restore spoofchk
-	if conf.SpoofChk != "" {
-		if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.OrigVfState.SpoofChk); err != nil {
-			return fmt.Errorf("failed to restore spoofchk for vf %d: %v", conf.VFID, err)
+	if len(conf.Ethtool.Features) > 0 {
+		current, err := s.ethtool.Features(podifName)
+		if err != nil {
+			return s.ethtoolError(err, podifName, "features")
+		}
+		orig.Features = make(map[string]bool, len(conf.Ethtool.Features))
+		for name := range conf.Ethtool.Features {
+			orig.Features[name] = current[name]
+		}
+
+		// Recorded first, as ChangeFeatures can fail on a fixed feature once the others are changed
+		journal.Record("ethtool features", undo(func() error {
+			return s.ethtool.ChangeFeatures(podifName, orig.Features)
+		}))
+		logging.Debug("Setting VF features", vfLogFields(conf, "link", podifName, "features", conf.Ethtool.Features)...)
+		if err = s.ethtool.ChangeFeatures(podifName, conf.Ethtool.Features); err != nil {
+			return s.ethtoolError(err, podifName, "features")
 		}
 	}
 
-	// Restore the original administrative MAC address
-	if conf.MAC != "" {
-		// when we restore the original hardware mac address we may get a device or resource busy. so we introduce retry
-		if err := utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.OrigVfState.AdminMAC); err != nil {
-			return fmt.Errorf("failed to restore original administrative MAC address %s: %v", conf.OrigVfState.AdminMAC, err)
+	if conf.Ethtool.Ring != nil {
+		logging.Debug("Setting VF ring sizes", vfLogFields(conf, "link", podifName)...)
+		prev, err := s.setRing(podifName, conf.Ethtool.Ring)
+		if err != nil {
+			return s.ethtoolError(err, podifName, "ring sizes")
 		}
+		orig.Ring = prev
+		journal.Record("ethtool ring", undo(func() error {
+			_, err := s.setRing(podifName, orig.Ring)
+			return err
+		}))
 	}
 
-	// Restore VF trust
-	if conf.Trust != "" {
-		if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.OrigVfState.Trust); err != nil {
-			return fmt.Errorf("failed to set trust for vf %d: %v", conf.VFID, err)
+	if conf.Ethtool.Channels != nil {
+		logging.Debug("Setting VF channel counts", vfLogFields(conf, "link", podifName)...)
+		prev, err := s.setChannels(podifName, conf.Ethtool.Channels)
+		if err != nil {
+			return s.ethtoolError(err, podifName, "channel counts")
 		}
+		orig.Channels = prev
+		journal.Record("ethtool channels", undo(func() error {
+			_, err := s.setChannels(podifName, orig.Channels)
+			return err
+		}))
 	}
 
-	// Restore rate limiting
-	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
-		if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, conf.OrigVfState.MinTxRate, conf.OrigVfState.MaxTxRate); err != nil {
-			return fmt.Errorf("failed to disable rate limiting for vf %d %v", conf.VFID, err)
+	return nil
+}
+
+// setPodSysctls writes the per-interface sysctls of conf for the Pod IF podifName, in sorted key order
+// The interface of the keys is either podifName or utils.IfSysctlPlaceholder. It runs in the Pod netns.
+// The sysctls aren't restored on release, the kernel resets them when the VF leaves the Pod netns.
+func (s *sriovManager) setPodSysctls(conf *sriovtypes.NetConf, podifName string) error {
+	keys := make([]string, 0, len(conf.Sysctl))
+	for key := range conf.Sysctl {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		ipVersion, ifName, param, err := utils.ParseIfSysctl(key)
+		if err != nil {
+			return srioverrors.InvalidConfig("invalid sysctl: %v", err)
+		}
+		if ifName != podifName && ifName != utils.IfSysctlPlaceholder {
+			return srioverrors.InvalidConfig("sysctl %s is not for the pod interface %s", key, podifName)
+		}
+
+		value := conf.Sysctl[key]
+		logging.Debug("Setting VF sysctl", vfLogFields(conf, "link", podifName, "sysctl", key, "value", value)...)
+		if err = s.utils.SetIfSysctl(ipVersion, podifName, param, value); err != nil {
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				return srioverrors.New(cnitypes.ErrInvalidNetworkConfig, err, "unknown sysctl %s", key)
+			case errors.Is(err, syscall.EINVAL):
+				return srioverrors.New(cnitypes.ErrInvalidNetworkConfig, err, "invalid value %q for sysctl %s", value, key)
+			}
+			return fmt.Errorf("failed to set sysctl %s to %q: %w", key, value, err)
 		}
 	}
 
-	// Restore link state to `auto`
-	if conf.LinkState != "" {
-		// Reset only when link_state was explicitly specified, to  accommodate for drivers / NICs
-		// that don't support the netlink command (e.g. igb driver)
-		if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, conf.OrigVfState.LinkState); err != nil {
-			return fmt.Errorf("failed to set link state to auto for vf %d: %v", conf.VFID, err)
+	return nil
+}
+
+// restoreEthtool restores the original values of the ethtool settings of the VF netdev ifName saved at setup
+// A failure doesn't stop the other settings from being restored.
+func (s *sriovManager) restoreEthtool(conf *sriovtypes.NetConf, ifName string) error {
+	orig := conf.OrigVfState.Ethtool
+	if orig == nil {
+		return nil
+	}
+
+	logging.Debug("Restoring VF ethtool settings", vfLogFields(conf, "link", ifName)...)
+	var errs []error
+	if len(orig.Features) > 0 {
+		if err := s.ethtool.ChangeFeatures(ifName, orig.Features); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore original features of %s: %v", ifName, err))
+		}
+	}
+	if orig.Ring != nil {
+		if _, err := s.setRing(ifName, orig.Ring); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore original ring sizes of %s: %v", ifName, err))
+		}
+	}
+	if orig.Channels != nil {
+		if _, err := s.setChannels(ifName, orig.Channels); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore original channel counts of %s: %v", ifName, err))
 		}
 	}
+	return errors.Join(errs...)
+}
+
+// vfLogFields returns the fields identifying the VF of conf in log messages, followed by args
+func vfLogFields(conf *sriovtypes.NetConf, args ...interface{}) []interface{} {
+	if conf.SFNum != nil {
+		return append([]interface{}{"pf", conf.Master, "sfNum", *conf.SFNum, "auxDev", conf.DeviceID}, args...)
+	}
+	return append([]interface{}{"pf", conf.Master, "vfID", conf.VFID, "pciAddr", conf.DeviceID}, args...)
+}
 
+func getVfInfo(link netlink.Link, id int) *netlink.VfInfo {
+	attrs := link.Attrs()
+	for _, vf := range attrs.Vfs {
+		if vf.ID == id {
+			return &vf
+		}
+	}
 	return nil
 }
+
+// renameLink renames the link oldName to newName in the current netns
+func (s *sriovManager) renameLink(oldName, newName string) error {
+	linkObj, err := s.nLink.LinkByName(oldName)
+	if err != nil {
+		return fmt.Errorf("failed to get netlink device with name %s: %q", oldName, err)
+	}
+	return s.nLink.LinkSetName(linkObj, newName)
+}
+
+// netlinkError explains the error of a netlink request setting attr of the VF of conf through its PF
+func (s *sriovManager) netlinkError(err error, conf *sriovtypes.NetConf, attr string) error {
+	driver, driverErr := utils.GetDriverName(conf.Master)
+	if driverErr != nil {
+		driver = "unknown"
+	}
+	return srioverrors.Netlink(err, conf.Master, driver, conf.VFID, attr)
+}
+
+// sysfsError explains the error of a write to the driver specific sysfs attribute setting attr of the VF of conf
+func (s *sriovManager) sysfsError(err error, conf *sriovtypes.NetConf, attr string) error {
+	driver, driverErr := utils.GetDriverName(conf.Master)
+	if driverErr != nil {
+		driver = "unknown"
+	}
+	return srioverrors.Sysfs(err, conf.Master, driver, conf.VFID, attr)
+}
+
+// setVfTrunk replaces the VLANs from in the trunk of the VF of conf with the VLANs to
+func (s *sriovManager) setVfTrunk(conf *sriovtypes.NetConf, from, to string) error {
+	if from != "" {
+		if err := s.sysfs.VfTrunkRemove(conf.Master, conf.VFID, from); err != nil {
+			return err
+		}
+	}
+	if to != "" {
+		return s.sysfs.VfTrunkAdd(conf.Master, conf.VFID, to)
+	}
+	return nil
+}
+
+// setVfMirror moves the mirroring of the direction traffic of the VF of conf from the VF from to the VF to,
+// either of which may be utils.MirrorOff
+func (s *sriovManager) setVfMirror(conf *sriovtypes.NetConf, direction string, from, to int) error {
+	if from == to {
+		return nil
+	}
+	if from != utils.MirrorOff {
+		if err := s.sysfs.VfMirrorRemove(conf.Master, conf.VFID, direction, from); err != nil {
+			return err
+		}
+	}
+	if to != utils.MirrorOff {
+		return s.sysfs.VfMirrorAdd(conf.Master, conf.VFID, direction, to)
+	}
+	return nil
+}
+
+// vfMirror is the mirroring of the traffic of a VF in one direction, as configured in NetConf
+type vfMirror struct {
+	direction string
+	target    int
+	orig      *int
+}
+
+// vfMirrors returns the mirrorings configured for the VF of conf
+func vfMirrors(conf *sriovtypes.NetConf) []vfMirror {
+	var mirrors []vfMirror
+	if conf.IngressMirror != nil {
+		mirrors = append(mirrors, vfMirror{utils.MirrorIngress, *conf.IngressMirror, &conf.OrigVfState.IngressMirror})
+	}
+	if conf.EgressMirror != nil {
+		mirrors = append(mirrors, vfMirror{utils.MirrorEgress, *conf.EgressMirror, &conf.OrigVfState.EgressMirror})
+	}
+	return mirrors
+}
+
+// DetectSwitchdev sets the representor of the VF or SF of conf when the eswitch of its PF is in switchdev mode,
+// and clears it otherwise. SFs only exist in switchdev mode.
+func (s *sriovManager) DetectSwitchdev(conf *sriovtypes.NetConf) error {
+	conf.Representor = ""
+
+	pfPciAddr, err := s.utils.GetPfPciAddress(conf.Master)
+	if err != nil {
+		return fmt.Errorf("failed to get pci address of PF %s: %v", conf.Master, err)
+	}
+	dev, err := s.devlink.DevLinkGetDeviceByName("pci", pfPciAddr)
+	switchdev := err == nil && dev.Attrs.Eswitch.Mode == utils.EswitchModeSwitchdev
+	if !switchdev && conf.SFNum != nil {
+		return srioverrors.InvalidConfig("scalable function %s requires the eswitch of PF %s to be in switchdev mode", conf.DeviceID, conf.Master)
+	}
+	if err != nil {
+		// PF drivers without devlink support only have the legacy mode
+		logging.Debug("PF has no devlink device, assuming legacy eswitch mode", "pf", conf.Master, "error", err)
+		return nil
+	}
+	if !switchdev {
+		return nil
+	}
+
+	if conf.SFNum != nil {
+		if conf.Representor, err = s.utils.GetSfRepresentor(conf.Master, *conf.SFNum); err != nil {
+			return fmt.Errorf("failed to find representor of sf %d of PF %s: %v", *conf.SFNum, conf.Master, err)
+		}
+	} else if conf.Representor, err = s.utils.GetVfRepresentor(conf.Master, conf.VFID); err != nil {
+		return fmt.Errorf("failed to find representor of vf %d of switchdev PF %s: %v", conf.VFID, conf.Master, err)
+	}
+	logging.Debug("PF is in switchdev mode", vfLogFields(conf, "representor", conf.Representor)...)
+	return nil
+}
+
+// representorPort returns the devlink port of the representor of the VF of conf, along with its port function
+func (s *sriovManager) representorPort(conf *sriovtypes.NetConf) (*netlink.DevlinkPort, error) {
+	ports, err := s.devlink.DevLinkGetAllPortList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devlink ports: %v", err)
+	}
+	for _, port := range ports {
+		if port.NetdeviceName != conf.Representor {
+			continue
+		}
+		if port.Fn == nil {
+			return nil, fmt.Errorf("devlink port %s/%s/%d of representor %s has no port function",
+				port.BusName, port.DeviceName, port.PortIndex, conf.Representor)
+		}
+		return port, nil
+	}
+	return nil, fmt.Errorf("devlink port of representor %s not found", conf.Representor)
+}
+
+// setPortFnMAC sets the MAC address of the VF of conf through the devlink port function of its representor
+// Equivalent to: `devlink port function set $port hw_addr $mac`
+func (s *sriovManager) setPortFnMAC(conf *sriovtypes.NetConf, mac string) error {
+	hwAddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("failed to parse MAC address %s: %v", mac, err)
+	}
+	port, err := s.representorPort(conf)
+	if err != nil {
+		return err
+	}
+	return s.devlink.DevlinkPortFnSet(port.BusName, port.DeviceName, port.PortIndex, netlink.DevlinkPortFnSetAttrs{
+		FnAttrs:     netlink.DevlinkPortFn{HwAddr: hwAddr},
+		HwAddrValid: true,
+	})
+}
+
+// resetPortFnCaps sets the port function capabilities of the VF of conf back to utils.PortFnCapsDefault.
+// Only the capabilities that differ are set, since drivers may reject any change while the VF driver is bound,
+// and nothing is done when the port function reports no capabilities.
+func (s *sriovManager) resetPortFnCaps(conf *sriovtypes.NetConf) error {
+	port, err := s.representorPort(conf)
+	if err != nil {
+		return err
+	}
+	caps, err := s.devlink.DevlinkPortFnCapsGet(port.BusName, port.DeviceName, port.PortIndex)
+	if errors.Is(err, syscall.EOPNOTSUPP) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	selector := (caps ^ utils.PortFnCapsDefault) & utils.PortFnCapsAll
+	if selector == 0 {
+		return nil
+	}
+	return s.devlink.DevlinkPortFnCapsSet(port.BusName, port.DeviceName, port.PortIndex, utils.PortFnCapsDefault, selector)
+}
+
+// portFnCaps returns the port function capability bits configured in conf, along with the selector of the
+// configured ones
+func portFnCaps(conf *sriovtypes.NetConf) (caps, selector uint32) {
+	if conf.PortFunction == nil {
+		return 0, 0
+	}
+	for _, attr := range []struct {
+		value string
+		bit   uint32
+	}{
+		{conf.PortFunction.Roce, utils.PortFnCapRoce},
+		{conf.PortFunction.Migratable, utils.PortFnCapMigratable},
+		{conf.PortFunction.IpsecCrypto, utils.PortFnCapIpsecCrypto},
+	} {
+		if attr.value == "" {
+			continue
+		}
+		selector |= attr.bit
+		if attr.value == "on" {
+			caps |= attr.bit
+		}
+	}
+	return caps, selector
+}
+
+// setPortFnCaps sets the port function capability bits of selector of the VF of conf to their value in caps
+func (s *sriovManager) setPortFnCaps(conf *sriovtypes.NetConf, caps, selector uint32) error {
+	port, err := s.representorPort(conf)
+	if err != nil {
+		return err
+	}
+	return s.devlink.DevlinkPortFnCapsSet(port.BusName, port.DeviceName, port.PortIndex, caps, selector)
+}
+
+// hasRateConfig tells whether conf configures the devlink rate leaf of its VF
+func hasRateConfig(conf *sriovtypes.NetConf) bool {
+	return conf.RateGroup != "" || conf.TxShare != nil || conf.TxMax != nil
+}
+
+// mbpsToBytes converts a rate in Mbps to the bytes per second unit of devlink rate objects
+func mbpsToBytes(mbps int) uint64 {
+	return uint64(mbps) * 1000 * 1000 / 8
+}
+
+// rateLeafConfig returns the rates, in bytes per second, and the parent node of the rate leaf of the VF of conf,
+// keeping the original value of the ones conf doesn't configure
+func rateLeafConfig(conf *sriovtypes.NetConf) (txShare, txMax uint64, parent string) {
+	orig := conf.OrigVfState.RateLeaf
+	txShare, txMax, parent = orig.TxShare, orig.TxMax, orig.Parent
+	if conf.TxShare != nil {
+		txShare = mbpsToBytes(*conf.TxShare)
+	}
+	if conf.TxMax != nil {
+		txMax = mbpsToBytes(*conf.TxMax)
+	}
+	if conf.RateGroup != "" {
+		parent = conf.RateGroup
+	}
+	return txShare, txMax, parent
+}
+
+// rateLeaf returns the devlink rate leaf of the devlink port portIndex of bus/device
+func (s *sriovManager) rateLeaf(bus, device string, portIndex uint32) (*sriovtypes.DevlinkRate, error) {
+	rates, err := s.devlink.DevlinkRateList(bus, device)
+	if err != nil {
+		return nil, err
+	}
+	for _, rate := range rates {
+		if rate.Leaf && rate.PortIndex == portIndex {
+			return rate, nil
+		}
+	}
+	return nil, fmt.Errorf("devlink port %s/%s/%d has no rate leaf", bus, device, portIndex)
+}
+
+// ensureRateGroup creates the rate node name next to leaf if it doesn't exist yet, and tells whether it created it
+// Equivalent to: `devlink port function rate add $bus/$device/$name`
+func (s *sriovManager) ensureRateGroup(leaf *sriovtypes.DevlinkRate, name string) (bool, error) {
+	rates, err := s.devlink.DevlinkRateList(leaf.BusName, leaf.DeviceName)
+	if err != nil {
+		return false, err
+	}
+	for _, rate := range rates {
+		if !rate.Leaf && rate.NodeName == name {
+			return false, nil
+		}
+	}
+	err = s.devlink.DevlinkRateNodeNew(leaf.BusName, leaf.DeviceName, name)
+	if errors.Is(err, syscall.EEXIST) {
+		// created meanwhile by the ADD of another VF of the group
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// deleteRateGroupIfUnused deletes the rate node name of bus/device when no rate object has it as parent anymore
+// Equivalent to: `devlink port function rate del $bus/$device/$name`
+func (s *sriovManager) deleteRateGroupIfUnused(bus, device, name string) error {
+	rates, err := s.devlink.DevlinkRateList(bus, device)
+	if err != nil {
+		return err
+	}
+	for _, rate := range rates {
+		if rate.Parent == name {
+			return nil
+		}
+	}
+	err = s.devlink.DevlinkRateNodeDel(bus, device, name)
+	if errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.EBUSY) {
+		// deleted or joined meanwhile by the DEL or ADD of another VF of the group
+		return nil
+	}
+	return err
+}
+
+// restoreRateLeaf restores the original rates and parent node of the rate leaf of the VF of conf
+func (s *sriovManager) restoreRateLeaf(conf *sriovtypes.NetConf) error {
+	leaf := conf.OrigVfState.RateLeaf
+	return s.devlink.DevlinkRateLeafSet(leaf.BusName, leaf.DeviceName, leaf.PortIndex, leaf.TxShare, leaf.TxMax, leaf.Parent)
+}
+
+// legacyOnlySettings returns the NetConf settings of conf that are applied through the IFLA_VF_* attributes of the
+// PF or its driver sysfs interface, which only work in legacy eswitch mode
+func legacyOnlySettings(conf *sriovtypes.NetConf) []string {
+	var settings []string
+	if conf.Vlan != nil && *conf.Vlan != 0 {
+		settings = append(settings, "vlan")
+	}
+	if conf.VlanQoS != nil && *conf.VlanQoS != 0 {
+		settings = append(settings, "vlanQoS")
+	}
+	if conf.VlanProto != "" {
+		settings = append(settings, "vlanProto")
+	}
+	if conf.VlanTrunk != "" {
+		settings = append(settings, "vlanTrunk")
+	}
+	if conf.IngressMirror != nil || conf.EgressMirror != nil {
+		settings = append(settings, "ingressMirror/egressMirror")
+	}
+	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
+		settings = append(settings, "min_tx_rate/max_tx_rate")
+	}
+	if conf.SpoofChk != "" {
+		settings = append(settings, "spoofchk")
+	}
+	if conf.Trust != "" {
+		settings = append(settings, "trust")
+	}
+	if conf.LinkState != "" {
+		settings = append(settings, "link_state")
+	}
+	return settings
+}
+
+// switchdevOnlySettings returns the NetConf settings of conf that are applied through devlink port functions or rate
+// objects, which only exist in switchdev eswitch mode
+func switchdevOnlySettings(conf *sriovtypes.NetConf) []string {
+	var settings []string
+	if conf.PortFunction != nil {
+		settings = append(settings, "portFunction")
+	}
+	if conf.RateGroup != "" {
+		settings = append(settings, "rateGroup")
+	}
+	if conf.TxShare != nil || conf.TxMax != nil {
+		settings = append(settings, "txShare/txMax")
+	}
+	return settings
+}
+
+// applySwitchdevConfig configures the VF of conf when its PF is in switchdev mode, where the IFLA_VF_* attributes
+// are ignored or rejected: the MAC address and capabilities are set through the devlink port function, the rates
+// through the devlink rate leaf of the VF and the representor is brought up
+func (s *sriovManager) applySwitchdevConfig(conf *sriovtypes.NetConf, journal *Journal) error {
+	if settings := legacyOnlySettings(conf); len(settings) > 0 {
+		return srioverrors.InvalidConfig("%s not supported when PF %s is in switchdev mode, configure the representor %s instead",
+			strings.Join(settings, ", "), conf.Master, conf.Representor)
+	}
+
+	// 1. Set mac address
+	if conf.MAC != "" {
+		logging.Debug("Setting VF port function MAC address", vfLogFields(conf, "mac", conf.MAC)...)
+		if err := s.setPortFnMAC(conf, conf.MAC); err != nil {
+			return s.netlinkError(err, conf, "port function MAC address "+conf.MAC)
+		}
+		journal.Record("mac", func() error {
+			return s.setPortFnMAC(conf, conf.OrigVfState.AdminMAC)
+		})
+	}
+
+	// 2. Set the port function capabilities
+	if caps, selector := portFnCaps(conf); selector != 0 {
+		logging.Debug("Setting VF port function capabilities", vfLogFields(conf, "caps", caps, "selector", selector)...)
+		if err := s.setPortFnCaps(conf, caps, selector); err != nil {
+			return s.netlinkError(err, conf, "port function capabilities")
+		}
+		journal.Record("port function caps", func() error {
+			return s.setPortFnCaps(conf, conf.OrigVfState.PortFnCaps, selector)
+		})
+	}
+
+	// 3. Attach the rate leaf to its rate group and set its rates
+	if leaf := conf.OrigVfState.RateLeaf; leaf != nil {
+		if conf.RateGroup != "" {
+			created, err := s.ensureRateGroup(leaf, conf.RateGroup)
+			if err != nil {
+				return s.netlinkError(err, conf, "rate group "+conf.RateGroup)
+			}
+			if created {
+				journal.Record("rate group", func() error {
+					return s.deleteRateGroupIfUnused(leaf.BusName, leaf.DeviceName, conf.RateGroup)
+				})
+			}
+		}
+		txShare, txMax, parent := rateLeafConfig(conf)
+		logging.Debug("Setting VF rate", vfLogFields(conf, "rateGroup", parent, "txShare", txShare, "txMax", txMax)...)
+		if err := s.devlink.DevlinkRateLeafSet(leaf.BusName, leaf.DeviceName, leaf.PortIndex, txShare, txMax, parent); err != nil {
+			return s.netlinkError(err, conf, "rate")
+		}
+		journal.Record("rate", func() error {
+			return s.restoreRateLeaf(conf)
+		})
+	}
+
+	// 4. Bring the representor up
+	repLink, err := s.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to lookup representor %q: %v", conf.Representor, err)
+	}
+	if !conf.OrigVfState.RepresentorUp {
+		logging.Debug("Setting VF representor up", vfLogFields(conf, "representor", conf.Representor)...)
+		if err = s.nLink.LinkSetUp(repLink); err != nil {
+			return fmt.Errorf("failed to set representor %s up: %v", conf.Representor, err)
+		}
+		journal.Record("representor up", func() error {
+			return s.nLink.LinkSetDown(repLink)
+		})
+	}
+
+	return nil
+}
+
+// ApplyVFConfig configure a VF with parameters given in NetConf, recording each applied attribute in journal
+func (s *sriovManager) ApplyVFConfig(conf *sriovtypes.NetConf, journal *Journal) error {
+	if conf.Representor != "" {
+		return s.applySwitchdevConfig(conf, journal)
+	}
+	if settings := switchdevOnlySettings(conf); len(settings) > 0 {
+		return srioverrors.InvalidConfig("%s requires the eswitch of PF %s to be in switchdev mode",
+			strings.Join(settings, ", "), conf.Master)
+	}
+
+	pfLink, err := s.nLink.LinkByName(conf.Master)
+	if err != nil {
+		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+	}
+	// 1. Set vlan
+	if conf.Vlan == nil {
+		vlan := new(int)
+		*vlan = 0
+		conf.Vlan = vlan
+	}
+	// set vlan protocol if present in the config, along with vlan qos
+	if conf.VlanProto != "" {
+		qos := 0
+		if conf.VlanQoS != nil {
+			qos = *conf.VlanQoS
+		}
+		proto := vlanProtoFromString(conf.VlanProto)
+		logging.Debug("Setting VF vlan, qos and protocol", vfLogFields(conf, "vlan", *conf.Vlan, "vlanQoS", qos, "vlanProto", conf.VlanProto)...)
+		if err = s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, *conf.Vlan, qos, proto); err != nil {
+			return s.netlinkError(err, conf, "vlan, qos and protocol "+conf.VlanProto)
+		}
+		journal.Record("vlan", func() error {
+			return s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS, conf.OrigVfState.VlanProto)
+		})
+	} else if conf.VlanQoS != nil {
+		// set vlan qos if present in the config
+		logging.Debug("Setting VF vlan and qos", vfLogFields(conf, "vlan", *conf.Vlan, "vlanQoS", *conf.VlanQoS)...)
+		if err = s.nLink.LinkSetVfVlanQos(pfLink, conf.VFID, *conf.Vlan, *conf.VlanQoS); err != nil {
+			return s.netlinkError(err, conf, "vlan and qos")
+		}
+		journal.Record("vlan", func() error {
+			return s.nLink.LinkSetVfVlanQos(pfLink, conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS)
+		})
+	} else {
+		// set vlan id field only
+		logging.Debug("Setting VF vlan", vfLogFields(conf, "vlan", *conf.Vlan)...)
+		if err = s.nLink.LinkSetVfVlan(pfLink, conf.VFID, *conf.Vlan); err != nil {
+			return s.netlinkError(err, conf, "vlan")
+		}
+		journal.Record("vlan", func() error {
+			return s.nLink.LinkSetVfVlan(pfLink, conf.VFID, conf.OrigVfState.Vlan)
+		})
+	}
+
+	// Set vlan trunk through the driver sysfs interface
+	if conf.VlanTrunk != "" {
+		logging.Debug("Setting VF vlan trunk", vfLogFields(conf, "vlanTrunk", conf.VlanTrunk)...)
+		if err = s.setVfTrunk(conf, conf.OrigVfState.VlanTrunk, conf.VlanTrunk); err != nil {
+			return s.sysfsError(err, conf, "vlanTrunk "+conf.VlanTrunk)
+		}
+		journal.Record("vlan_trunk", func() error {
+			return s.setVfTrunk(conf, conf.VlanTrunk, conf.OrigVfState.VlanTrunk)
+		})
+	}
+
+	// Set port mirroring through the driver sysfs interface
+	for _, mirror := range vfMirrors(conf) {
+		mirror := mirror
+		logging.Debug("Setting VF mirror", vfLogFields(conf, "direction", mirror.direction, "target", mirror.target)...)
+		if err = s.setVfMirror(conf, mirror.direction, *mirror.orig, mirror.target); err != nil {
+			return s.sysfsError(err, conf, fmt.Sprintf("%s mirror to vf %d", mirror.direction, mirror.target))
+		}
+		journal.Record(mirror.direction+"_mirror", func() error {
+			return s.setVfMirror(conf, mirror.direction, mirror.target, *mirror.orig)
+		})
+	}
+
+	// 2. Set mac address
+	if conf.MAC != "" {
+		// when we restore the original hardware mac address we may get a device or resource busy. so we introduce retry
+		logging.Debug("Setting VF administrative MAC address", vfLogFields(conf, "mac", conf.MAC)...)
+		if err := utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.MAC); err != nil {
+			return s.netlinkError(err, conf, "administrative MAC address "+conf.MAC)
+		}
+		journal.Record("mac", func() error {
+			return utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.OrigVfState.AdminMAC)
+		})
+	}
+
+	// 3. Set min/max tx link rate. 0 means no rate limiting. Support depends on NICs and driver.
+	var minTxRate, maxTxRate int
+	rateConfigured := false
+	if conf.MinTxRate != nil {
+		minTxRate = *conf.MinTxRate
+		rateConfigured = true
+	}
+
+	if conf.MaxTxRate != nil {
+		maxTxRate = *conf.MaxTxRate
+		rateConfigured = true
+	}
+
+	if rateConfigured {
+		logging.Debug("Setting VF tx rate", vfLogFields(conf, "minTxRate", minTxRate, "maxTxRate", maxTxRate)...)
+		if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, minTxRate, maxTxRate); err != nil {
+			return s.netlinkError(err, conf, fmt.Sprintf("min_tx_rate %d Mbps and max_tx_rate %d Mbps", minTxRate, maxTxRate))
+		}
+		journal.Record("rate", func() error {
+			return s.nLink.LinkSetVfRate(pfLink, conf.VFID, conf.OrigVfState.MinTxRate, conf.OrigVfState.MaxTxRate)
+		})
+	}
+
+	// 4. Set spoofchk flag
+	if conf.SpoofChk != "" {
+		spoofChk := false
+		if conf.SpoofChk == "on" {
+			spoofChk = true
+		}
+		logging.Debug("Setting VF spoofchk", vfLogFields(conf, "spoofchk", conf.SpoofChk)...)
+		if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, spoofChk); err != nil {
+			return s.netlinkError(err, conf, "spoofchk "+conf.SpoofChk)
+		}
+		journal.Record("spoofchk", func() error {
+			return s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.OrigVfState.SpoofChk)
+		})
+	}
+
+	// 5. Set trust flag
+	if conf.Trust != "" {
+		trust := false
+		if conf.Trust == "on" {
+			trust = true
+		}
+		logging.Debug("Setting VF trust", vfLogFields(conf, "trust", conf.Trust)...)
+		if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, trust); err != nil {
+			return s.netlinkError(err, conf, "trust "+conf.Trust)
+		}
+		journal.Record("trust", func() error {
+			return s.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.OrigVfState.Trust)
+		})
+	}
+
+	// 6. Set link state
+	if conf.LinkState != "" {
+		state, err := linkStateFromString(conf.LinkState)
+		if err != nil {
+			// the value should have been validated earlier, return error if we somehow got here
+			return srioverrors.InvalidConfig("%v when setting it for vf %d", err, conf.VFID)
+		}
+		logging.Debug("Setting VF link state", vfLogFields(conf, "linkState", conf.LinkState)...)
+		if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, state); err != nil {
+			return s.netlinkError(err, conf, "link_state "+conf.LinkState)
+		}
+		journal.Record("link_state", func() error {
+			return s.nLink.LinkSetVfState(pfLink, conf.VFID, conf.OrigVfState.LinkState)
+		})
+	}
+
+	return nil
+}
+
+// fillOriginalSwitchdevInfo fills the original state of the VF or SF of conf kept by its devlink port function,
+// devlink rate leaf and representor
+func (s *sriovManager) fillOriginalSwitchdevInfo(conf *sriovtypes.NetConf) error {
+	// In switchdev mode the MAC address is the one of the devlink port function
+	port, err := s.representorPort(conf)
+	if err != nil {
+		return err
+	}
+	conf.OrigVfState.AdminMAC = port.Fn.HwAddr.String()
+
+	repLink, err := s.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to lookup representor %q: %v", conf.Representor, err)
+	}
+	conf.OrigVfState.RepresentorUp = repLink.Attrs().Flags&net.FlagUp != 0
+
+	// The capabilities are only read when they are going to be changed, not every kernel reports them
+	if _, selector := portFnCaps(conf); selector != 0 {
+		if conf.OrigVfState.PortFnCaps, err = s.devlink.DevlinkPortFnCapsGet(port.BusName, port.DeviceName, port.PortIndex); err != nil {
+			return s.netlinkError(err, conf, "port function capabilities")
+		}
+	}
+
+	// The rate leaf is cached along with the devlink device and port it belongs to, so that it can be
+	// restored even when the representor is gone
+	if hasRateConfig(conf) {
+		if conf.OrigVfState.RateLeaf, err = s.rateLeaf(port.BusName, port.DeviceName, port.PortIndex); err != nil {
+			return s.netlinkError(err, conf, "rate")
+		}
+	}
+
+	return nil
+}
+
+// FillOriginalVfInfo fills the original vf info
+func (s *sriovManager) FillOriginalVfInfo(conf *sriovtypes.NetConf) error {
+	// Scalable Functions have no IFLA_VF_* attributes, they are only configured through devlink
+	if conf.SFNum != nil {
+		return s.fillOriginalSwitchdevInfo(conf)
+	}
+
+	pfLink, err := s.nLink.LinkByName(conf.Master)
+	if err != nil {
+		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+	}
+	// Save current the VF state before modifying it
+	vfState := getVfInfo(pfLink, conf.VFID)
+	if vfState == nil {
+		return fmt.Errorf("failed to find vf %d", conf.VFID)
+	}
+	conf.OrigVfState.FillFromVfInfo(vfState)
+
+	if conf.Representor != "" {
+		if err = s.fillOriginalSwitchdevInfo(conf); err != nil {
+			return err
+		}
+	}
+
+	// The vlan protocol is not part of the VF info, and is only read when it is going to be changed
+	if conf.VlanProto != "" {
+		if conf.OrigVfState.VlanProto, err = s.nLink.LinkGetVfVlanProto(pfLink, conf.VFID); err != nil {
+			return fmt.Errorf("failed to get vlan protocol of vf %d: %v", conf.VFID, err)
+		}
+	}
+
+	// The vlan trunk is only exposed by some drivers, reading it checks that the driver supports it
+	if conf.VlanTrunk != "" {
+		if conf.OrigVfState.VlanTrunk, err = s.sysfs.VfTrunkGet(conf.Master, conf.VFID); err != nil {
+			return s.sysfsError(err, conf, "vlanTrunk")
+		}
+	}
+
+	// The mirrors are only exposed by some drivers, reading them checks that the driver supports them
+	for _, mirror := range vfMirrors(conf) {
+		if *mirror.orig, err = s.sysfs.VfMirrorGet(conf.Master, conf.VFID, mirror.direction); err != nil {
+			return s.sysfsError(err, conf, mirror.direction+" mirror")
+		}
+	}
+
+	return err
+}
+
+// ResetVFConfig reset a VF to its original state
+func (s *sriovManager) ResetVFConfig(conf *sriovtypes.NetConf) error {
+	logging.Debug("Resetting VF configuration", vfLogFields(conf)...)
+
+	if conf.Representor != "" {
+		return s.resetSwitchdevConfig(conf)
+	}
+
+	pfLink, err := s.nLink.LinkByName(conf.Master)
+	if err != nil {
+		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+	}
+
+	// Restore VLAN
+	if conf.VlanProto != "" {
+		logging.Debug("Restoring VF vlan and protocol", vfLogFields(conf, "vlan", conf.OrigVfState.Vlan, "vlanQoS", conf.OrigVfState.VlanQoS, "vlanProto", conf.OrigVfState.VlanProto)...)
+		if err = s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS, conf.OrigVfState.VlanProto); err != nil {
+			return s.netlinkError(err, conf, "original vlan and protocol")
+		}
+	} else if conf.Vlan != nil {
+		logging.Debug("Restoring VF vlan", vfLogFields(conf, "vlan", conf.OrigVfState.Vlan, "vlanQoS", conf.OrigVfState.VlanQoS)...)
+		if conf.VlanQoS != nil {
+			if err = s.nLink.LinkSetVfVlanQos(pfLink, conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS); err != nil {
+				return s.netlinkError(err, conf, "original vlan")
+			}
+		} else if err = s.nLink.LinkSetVfVlan(pfLink, conf.VFID, conf.OrigVfState.Vlan); err != nil {
+			return s.netlinkError(err, conf, "original vlan")
+		}
+	}
+
+	// Restore vlan trunk
+	if conf.VlanTrunk != "" {
+		logging.Debug("Restoring VF vlan trunk", vfLogFields(conf, "vlanTrunk", conf.OrigVfState.VlanTrunk)...)
+		if err = s.setVfTrunk(conf, conf.VlanTrunk, conf.OrigVfState.VlanTrunk); err != nil {
+			return s.sysfsError(err, conf, "original vlanTrunk")
+		}
+	}
+
+	// Clear mirrors, rather than restoring a mirror a previous tenant of the VF may have left behind
+	for _, mirror := range vfMirrors(conf) {
+		logging.Debug("Clearing VF mirror", vfLogFields(conf, "direction", mirror.direction, "target", mirror.target)...)
+		if err = s.setVfMirror(conf, mirror.direction, mirror.target, utils.MirrorOff); err != nil {
+			return s.sysfsError(err, conf, mirror.direction+" mirror")
+		}
+	}
+
+	// Restore spoofchk
+	if conf.SpoofChk != "" {
+		logging.Debug("Restoring VF spoofchk", vfLogFields(conf, "spoofchk", conf.OrigVfState.SpoofChk)...)
+		if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.OrigVfState.SpoofChk); err != nil {
+			return s.netlinkError(err, conf, "original spoofchk")
+		}
+	}
+
+	// Restore the original administrative MAC address
+	if conf.MAC != "" {
+		// when we restore the original hardware mac address we may get a device or resource busy. so we introduce retry
+		logging.Debug("Restoring VF administrative MAC address", vfLogFields(conf, "mac", conf.OrigVfState.AdminMAC)...)
+		if err := utils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.OrigVfState.AdminMAC); err != nil {
+			return s.netlinkError(err, conf, "original administrative MAC address "+conf.OrigVfState.AdminMAC)
+		}
+	}
+
+	// Restore VF trust
+	if conf.Trust != "" {
+		logging.Debug("Restoring VF trust", vfLogFields(conf, "trust", conf.OrigVfState.Trust)...)
+		if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.OrigVfState.Trust); err != nil {
+			return s.netlinkError(err, conf, "original trust")
+		}
+	}
+
+	// Restore rate limiting
+	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
+		logging.Debug("Restoring VF tx rate", vfLogFields(conf, "minTxRate", conf.OrigVfState.MinTxRate, "maxTxRate", conf.OrigVfState.MaxTxRate)...)
+		if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, conf.OrigVfState.MinTxRate, conf.OrigVfState.MaxTxRate); err != nil {
+			return s.netlinkError(err, conf, "original tx rate")
+		}
+	}
+
+	// Restore link state to `auto`
+	if conf.LinkState != "" {
+		// Reset only when link_state was explicitly specified, to  accommodate for drivers / NICs
+		// that don't support the netlink command (e.g. igb driver)
+		logging.Debug("Restoring VF link state", vfLogFields(conf, "linkState", conf.OrigVfState.LinkState)...)
+		if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, conf.OrigVfState.LinkState); err != nil {
+			return s.netlinkError(err, conf, "original link_state")
+		}
+	}
+
+	return nil
+}
+
+// resetSwitchdevConfig restores the port function attributes, the rate leaf and the representor state of the VF
+// of conf, whose PF is in switchdev mode
+func (s *sriovManager) resetSwitchdevConfig(conf *sriovtypes.NetConf) error {
+	if conf.MAC != "" {
+		logging.Debug("Restoring VF port function MAC address", vfLogFields(conf, "mac", conf.OrigVfState.AdminMAC)...)
+		if err := s.setPortFnMAC(conf, conf.OrigVfState.AdminMAC); err != nil {
+			return s.netlinkError(err, conf, "original port function MAC address "+conf.OrigVfState.AdminMAC)
+		}
+	}
+
+	if _, selector := portFnCaps(conf); selector != 0 {
+		logging.Debug("Restoring VF port function capabilities", vfLogFields(conf, "caps", conf.OrigVfState.PortFnCaps, "selector", selector)...)
+		if err := s.setPortFnCaps(conf, conf.OrigVfState.PortFnCaps, selector); err != nil {
+			return s.netlinkError(err, conf, "original port function capabilities")
+		}
+	}
+
+	// Detach the VF from its rate group first, the group is deleted along with its last member
+	if leaf := conf.OrigVfState.RateLeaf; leaf != nil {
+		logging.Debug("Restoring VF rate", vfLogFields(conf, "rateGroup", leaf.Parent, "txShare", leaf.TxShare, "txMax", leaf.TxMax)...)
+		if err := s.restoreRateLeaf(conf); err != nil {
+			return s.netlinkError(err, conf, "original rate")
+		}
+		if conf.RateGroup != "" && conf.RateGroup != leaf.Parent {
+			if err := s.deleteRateGroupIfUnused(leaf.BusName, leaf.DeviceName, conf.RateGroup); err != nil {
+				return fmt.Errorf("failed to delete rate group %s: %v", conf.RateGroup, err)
+			}
+		}
+	}
+
+	if !conf.OrigVfState.RepresentorUp {
+		repLink, err := s.nLink.LinkByName(conf.Representor)
+		if err != nil {
+			return fmt.Errorf("failed to lookup representor %q: %v", conf.Representor, err)
+		}
+		logging.Debug("Setting VF representor down", vfLogFields(conf, "representor", conf.Representor)...)
+		if err = s.nLink.LinkSetDown(repLink); err != nil {
+			return fmt.Errorf("failed to set representor %s down: %v", conf.Representor, err)
+		}
+	}
+
+	return nil
+}
+
+// ResetVFToDefault resets the VF of conf to a safe default profile when its original state is unknown:
+// no vlan nor configured vlan trunk or mirrors, a zero administrative MAC, spoof checking on, trust off,
+// no rate limiting and link state auto. In switchdev mode: a zero port function MAC address, the default
+// port function capabilities, no rate limiting and the representor down, as it is created.
+// Every setting is attempted even if an earlier one fails, and all the failures are returned.
+func (s *sriovManager) ResetVFToDefault(conf *sriovtypes.NetConf) error {
+	logging.Debug("Resetting VF configuration to defaults", vfLogFields(conf)...)
+
+	// In switchdev mode the MAC address is configured through the devlink port function, and the rates through
+	// the devlink rate leaf of the VF
+	if conf.Representor != "" {
+		var errs []error
+		if err := s.setPortFnMAC(conf, make(net.HardwareAddr, 6).String()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to reset vf %d port function MAC address: %v", conf.VFID, err))
+		}
+		if err := s.resetPortFnCaps(conf); err != nil {
+			errs = append(errs, fmt.Errorf("failed to reset vf %d port function capabilities: %v", conf.VFID, err))
+		}
+		if hasRateConfig(conf) {
+			port, err := s.representorPort(conf)
+			if err == nil {
+				err = s.devlink.DevlinkRateLeafSet(port.BusName, port.DeviceName, port.PortIndex, 0, 0, "")
+			}
+			if err == nil && conf.RateGroup != "" {
+				err = s.deleteRateGroupIfUnused(port.BusName, port.DeviceName, conf.RateGroup)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to reset rate of vf %d: %v", conf.VFID, err))
+			}
+		}
+		repLink, err := s.nLink.LinkByName(conf.Representor)
+		if err == nil {
+			err = s.nLink.LinkSetDown(repLink)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to set representor %s down: %v", conf.Representor, err))
+		}
+		return errors.Join(errs...)
+	}
+
+	pfLink, err := s.nLink.LinkByName(conf.Master)
+	if err != nil {
+		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+	}
+
+	var errs []error
+	if err = s.nLink.LinkSetVfVlanQos(pfLink, conf.VFID, 0, 0); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reset vf %d vlan: %v", conf.VFID, err))
+	}
+	if err = s.nLink.LinkSetVfHardwareAddr(pfLink, conf.VFID, make(net.HardwareAddr, 6)); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reset vf %d administrative MAC address: %v", conf.VFID, err))
+	}
+	if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, true); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reset spoofchk for vf %d: %v", conf.VFID, err))
+	}
+	if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, false); err != nil {
+		errs = append(errs, fmt.Errorf("failed to reset trust for vf %d: %v", conf.VFID, err))
+	}
+	if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, 0, 0); err != nil {
+		errs = append(errs, fmt.Errorf("failed to disable rate limiting for vf %d: %v", conf.VFID, err))
+	}
+	if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, netlink.VF_LINK_STATE_AUTO); err != nil {
+		errs = append(errs, fmt.Errorf("failed to set link state to auto for vf %d: %v", conf.VFID, err))
+	}
+	if conf.VlanTrunk != "" {
+		if err = s.sysfs.VfTrunkRemove(conf.Master, conf.VFID, conf.VlanTrunk); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove vlan trunk %s from vf %d: %v", conf.VlanTrunk, conf.VFID, err))
+		}
+	}
+	for _, mirror := range vfMirrors(conf) {
+		if err = s.sysfs.VfMirrorRemove(conf.Master, conf.VFID, mirror.direction, mirror.target); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s mirror of vf %d: %v", mirror.direction, conf.VFID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// CheckVFConfig verifies that the VF configuration reported by the PF still matches the parameters given in NetConf
+func (s *sriovManager) CheckVFConfig(conf *sriovtypes.NetConf) error {
+	// Scalable Functions have no IFLA_VF_* attributes, the settings compared to them are rejected for SFs
+	vfInfo := &netlink.VfInfo{}
+	var pfLink netlink.Link
+	if conf.SFNum == nil {
+		var err error
+		pfLink, err = s.nLink.LinkByName(conf.Master)
+		if err != nil {
+			return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+		}
+
+		if vfInfo = getVfInfo(pfLink, conf.VFID); vfInfo == nil {
+			return fmt.Errorf("failed to find vf %d", conf.VFID)
+		}
+	}
+
+	var mismatches []string
+	mismatch := func(attr string, expected, actual interface{}) {
+		mismatches = append(mismatches, fmt.Sprintf("%s: expected %v, found %v", attr, expected, actual))
+	}
+
+	if conf.VdpaDevice != nil {
+		vdpaDev, err := s.utils.GetVdpaDevice(conf.DeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to read vDPA device of vf %s: %v", conf.DeviceID, err)
+		}
+		if vdpaDev == nil {
+			mismatches = append(mismatches, fmt.Sprintf("vdpa: vf %s no longer has vDPA device %s", conf.DeviceID, conf.VdpaDevice.Name))
+		} else if vdpaDev.Driver != conf.VdpaDevice.Driver {
+			mismatch("vdpa driver", conf.VdpaDevice.Driver, vdpaDev.Driver)
+		}
+	} else if conf.DPDKMode {
+		hasDpdkDriver, err := s.utils.HasDpdkDriver(conf.DeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to read driver of vf %s: %v", conf.DeviceID, err)
+		}
+		if !hasDpdkDriver {
+			mismatches = append(mismatches, fmt.Sprintf("driver: vf %s is no longer bound to a dpdk driver", conf.DeviceID))
+		}
+	}
+
+	mac := vfInfo.Mac.String()
+	if conf.Representor != "" {
+		// In switchdev mode the MAC address is set through the devlink port function
+		port, err := s.representorPort(conf)
+		if err != nil {
+			return err
+		}
+		mac = port.Fn.HwAddr.String()
+
+		if caps, selector := portFnCaps(conf); selector != 0 {
+			actual, err := s.devlink.DevlinkPortFnCapsGet(port.BusName, port.DeviceName, port.PortIndex)
+			if err != nil {
+				return fmt.Errorf("failed to get port function capabilities of vf %d: %v", conf.VFID, err)
+			}
+			if actual&selector != caps {
+				mismatch("portFunction capabilities", fmt.Sprintf("%#x", caps), fmt.Sprintf("%#x", actual&selector))
+			}
+		}
+
+		if hasRateConfig(conf) {
+			leaf, err := s.rateLeaf(port.BusName, port.DeviceName, port.PortIndex)
+			if err != nil {
+				return fmt.Errorf("failed to get rate of vf %d: %v", conf.VFID, err)
+			}
+			if conf.RateGroup != "" && leaf.Parent != conf.RateGroup {
+				mismatch("rateGroup", conf.RateGroup, leaf.Parent)
+			}
+			if conf.TxShare != nil && leaf.TxShare != mbpsToBytes(*conf.TxShare) {
+				mismatch("txShare", fmt.Sprintf("%d bytes/s", mbpsToBytes(*conf.TxShare)), fmt.Sprintf("%d bytes/s", leaf.TxShare))
+			}
+			if conf.TxMax != nil && leaf.TxMax != mbpsToBytes(*conf.TxMax) {
+				mismatch("txMax", fmt.Sprintf("%d bytes/s", mbpsToBytes(*conf.TxMax)), fmt.Sprintf("%d bytes/s", leaf.TxMax))
+			}
+		}
+	}
+	if conf.MAC != "" && !strings.EqualFold(mac, conf.MAC) {
+		mismatch("mac", conf.MAC, mac)
+	}
+
+	if conf.Vlan != nil && vfInfo.Vlan != *conf.Vlan {
+		mismatch("vlan", *conf.Vlan, vfInfo.Vlan)
+	}
+
+	if conf.VlanQoS != nil && vfInfo.Qos != *conf.VlanQoS {
+		mismatch("vlanQoS", *conf.VlanQoS, vfInfo.Qos)
+	}
+
+	if conf.VlanProto != "" {
+		proto, err := s.nLink.LinkGetVfVlanProto(pfLink, conf.VFID)
+		if err != nil {
+			return fmt.Errorf("failed to get vlan protocol of vf %d: %v", conf.VFID, err)
+		}
+		if proto != vlanProtoFromString(conf.VlanProto) {
+			mismatch("vlanProto", conf.VlanProto, netlink.VlanProtocol(proto))
+		}
+	}
+
+	if conf.VlanTrunk != "" {
+		trunk, err := s.sysfs.VfTrunkGet(conf.Master, conf.VFID)
+		if err != nil {
+			return fmt.Errorf("failed to get vlan trunk of vf %d: %v", conf.VFID, err)
+		}
+		if !sameVlanTrunk(trunk, conf.VlanTrunk) {
+			mismatch("vlanTrunk", conf.VlanTrunk, trunk)
+		}
+	}
+
+	for _, mirror := range vfMirrors(conf) {
+		target, err := s.sysfs.VfMirrorGet(conf.Master, conf.VFID, mirror.direction)
+		if err != nil {
+			return fmt.Errorf("failed to get %s mirror of vf %d: %v", mirror.direction, conf.VFID, err)
+		}
+		if target != mirror.target {
+			mismatch(mirror.direction+"Mirror", mirror.target, mirrorString(target))
+		}
+	}
+
+	if conf.SpoofChk != "" && vfInfo.Spoofchk != (conf.SpoofChk == "on") {
+		mismatch("spoofchk", conf.SpoofChk, onOff(vfInfo.Spoofchk))
+	}
+
+	if conf.Trust != "" && (vfInfo.Trust != 0) != (conf.Trust == "on") {
+		mismatch("trust", conf.Trust, onOff(vfInfo.Trust != 0))
+	}
+
+	if conf.MinTxRate != nil && int(vfInfo.MinTxRate) != *conf.MinTxRate {
+		mismatch("min_tx_rate", *conf.MinTxRate, vfInfo.MinTxRate)
+	}
+
+	if conf.MaxTxRate != nil && int(vfInfo.MaxTxRate) != *conf.MaxTxRate {
+		mismatch("max_tx_rate", *conf.MaxTxRate, vfInfo.MaxTxRate)
+	}
+
+	if conf.LinkState != "" {
+		state, err := linkStateFromString(conf.LinkState)
+		if err != nil {
+			return err
+		}
+		if vfInfo.LinkState != state {
+			mismatch("link_state", conf.LinkState, vfInfo.LinkState)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return fmt.Errorf("vf %d of %s does not match the requested configuration: %s",
+			conf.VFID, conf.Master, strings.Join(mismatches, "; "))
+	}
+
+	return nil
+}
+
+// CheckVF verifies that the pod interface exists in the Pod netns with the expected MAC address
+func (s *sriovManager) CheckVF(podifName string, expectedMAC string, netns ns.NetNS) error {
+	return netns.Do(func(_ ns.NetNS) error {
+		linkObj, err := s.nLink.LinkByName(podifName)
+		if err != nil {
+			return fmt.Errorf("failed to get netlink device with name %s: %q", podifName, err)
+		}
+
+		if expectedMAC != "" && !strings.EqualFold(linkObj.Attrs().HardwareAddr.String(), expectedMAC) {
+			return fmt.Errorf("interface %s mac address mismatch: expected %s, found %s",
+				podifName, expectedMAC, linkObj.Attrs().HardwareAddr.String())
+		}
+
+		return nil
+	})
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+// mirrorString returns the VF a mirror targets, or "off"
+func mirrorString(target int) string {
+	if target == utils.MirrorOff {
+		return "off"
+	}
+	return strconv.Itoa(target)
+}
+
+// sameVlanTrunk reports whether the VLAN trunks a and b, comma separated lists of VLAN IDs and ranges,
+// hold the same VLANs, whatever the way the driver lists them
+func sameVlanTrunk(a, b string) bool {
+	vlansA, errA := vlanTrunkVlans(a)
+	vlansB, errB := vlanTrunkVlans(b)
+	return errA == nil && errB == nil && vlansA == vlansB
+}
+
+// vlanTrunkVlans returns the set of VLANs of a VLAN trunk
+func vlanTrunkVlans(trunk string) ([4095]bool, error) {
+	var vlans [4095]bool
+	for _, r := range strings.Split(trunk, ",") {
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(r, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return vlans, err
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+				return vlans, err
+			}
+		}
+		if start < 0 || end >= len(vlans) || start > end {
+			return vlans, fmt.Errorf("invalid vlan range %q", r)
+		}
+		for id := start; id <= end; id++ {
+			vlans[id] = true
+		}
+	}
+	return vlans, nil
+}
+
+// vlanProtoFromString returns the ethertype of a vlanProto value, validated by LoadConf
+func vlanProtoFromString(vlanProto string) int {
+	if vlanProto == sriovtypes.VlanProto8021ad {
+		return int(netlink.VLAN_PROTOCOL_8021AD)
+	}
+	return int(netlink.VLAN_PROTOCOL_8021Q)
+}
+
+func linkStateFromString(linkState string) (uint32, error) {
+	switch linkState {
+	case "auto":
+		return netlink.VF_LINK_STATE_AUTO, nil
+	case "enable":
+		return netlink.VF_LINK_STATE_ENABLE, nil
+	case "disable":
+		return netlink.VF_LINK_STATE_DISABLE, nil
+	}
+	return 0, fmt.Errorf("unknown link state %s", linkState)
+}
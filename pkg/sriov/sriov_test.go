@@ -1,18 +1,26 @@
 package sriov
 
 import (
+	"errors"
+	"fmt"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils"
 	"net"
+	"os"
+	"syscall"
 
+	cnitypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/containernetworking/plugins/pkg/testutils"
+	srioverrors "github.com/k8snetworkplumbingwg/sriov-cni/pkg/errors"
 	"github.com/k8snetworkplumbingwg/sriov-cni/pkg/sriov/mocks"
 	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	mocks_utils "github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils/mocks"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/safchain/ethtool"
 	"github.com/stretchr/testify/mock"
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
 )
 
 var _ = Describe("Sriov", func() {
@@ -73,7 +81,7 @@ var _ = Describe("Sriov", func() {
 			mocked.On("LinkSetVfVlanQos", mock.Anything, mock.AnythingOfType("int"), mock.AnythingOfType("int"), mock.AnythingOfType("int")).Return(nil)
 			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
 			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
-			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			err = sm.SetupVF(netconf, podifName, targetNetNS, nil)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(netconf.OrigVfState.EffectiveMAC).To(Equal("6e:16:06:0e:b7:e9"))
 		})
@@ -116,10 +124,241 @@ var _ = Describe("Sriov", func() {
 			mocked.On("LinkSetUp", fakeLink).Return(nil)
 			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
 			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
-			err = sm.SetupVF(netconf, podifName, targetNetNS)
+			err = sm.SetupVF(netconf, podifName, targetNetNS, nil)
 			Expect(err).NotTo(HaveOccurred())
 			mocked.AssertExpectations(t)
 		})
+		It("Setting VF's MTU, transmit queue length and flags", func() {
+			var targetNetNS ns.NetNS
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			mtu, txQueueLen := 9000, 5000
+			netconf.MTU = &mtu
+			netconf.TxQueueLen = &txQueueLen
+			netconf.Promisc = "on"
+			netconf.Allmulti = "off"
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{
+				Index:    1000,
+				Name:     "dummylink",
+				MTU:      1500,
+				TxQLen:   1000,
+				Allmulti: 1,
+			}}
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "enp175s0f1", MTU: 9000}}
+
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkGetMaxMTU", fakeLink).Return(9702, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetMTU", fakeLink, 9000).Return(nil)
+			mocked.On("LinkSetTxQLen", fakeLink, 5000).Return(nil)
+			mocked.On("LinkSetPromiscOn", fakeLink).Return(nil)
+			mocked.On("LinkSetAllmulticastOff", fakeLink).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			journal := NewJournal()
+			err = sm.SetupVF(netconf, podifName, targetNetNS, journal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(journal.Steps()).To(ContainElements("mtu", "txqueuelen", "promisc", "allmulti"))
+			Expect(netconf.OrigVfState.MTU).To(Equal(1500))
+			Expect(netconf.OrigVfState.TxQueueLen).To(Equal(1000))
+			Expect(netconf.OrigVfState.Promisc).To(BeFalse())
+			Expect(netconf.OrigVfState.Allmulti).To(BeTrue())
+			mocked.AssertExpectations(t)
+		})
+		It("Setting VF's ethtool features, ring sizes and channel counts", func() {
+			var targetNetNS ns.NetNS
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedEthtool := &mocks_utils.EthtoolManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			rx, combined := uint32(4096), uint32(4)
+			features := map[string]bool{"rx-gro": false, "rx-vlan-filter": true}
+			netconf.Ethtool = &sriovtypes.EthtoolConf{
+				Features: features,
+				Ring:     &sriovtypes.EthtoolRing{Rx: &rx},
+				Channels: &sriovtypes.EthtoolChannels{Combined: &combined},
+			}
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedEthtool.On("Features", podifName).Return(map[string]bool{"rx-gro": true, "rx-vlan-filter": false, "rx-lro": false}, nil)
+			mockedEthtool.On("ChangeFeatures", podifName, features).Return(nil)
+			mockedEthtool.On("GetRing", podifName).Return(uint32(512), uint32(512), nil)
+			mockedEthtool.On("SetRing", podifName, uint32(4096), uint32(512)).Return(nil)
+			mockedEthtool.On("GetChannels", podifName).Return(ethtool.Channels{CombinedCount: 16}, nil)
+			mockedEthtool.On("SetChannels", podifName, ethtool.Channels{CombinedCount: 4}).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			sm := sriovManager{nLink: mocked, ethtool: mockedEthtool, utils: mockedPciUtils}
+			journal := NewJournal()
+			err = sm.SetupVF(netconf, podifName, targetNetNS, journal)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(journal.Steps()).To(ContainElements("ethtool features", "ethtool ring", "ethtool channels"))
+
+			orig := netconf.OrigVfState.Ethtool
+			Expect(orig.Features).To(Equal(map[string]bool{"rx-gro": true, "rx-vlan-filter": false}))
+			Expect(*orig.Ring.Rx).To(Equal(uint32(512)))
+			Expect(orig.Ring.Tx).To(BeNil())
+			Expect(*orig.Channels.Combined).To(Equal(uint32(16)))
+			mockedEthtool.AssertExpectations(t)
+		})
+		It("Reporting an ethtool feature the driver doesn't support", func() {
+			var targetNetNS ns.NetNS
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedEthtool := &mocks_utils.EthtoolManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			features := map[string]bool{"rx-lro": true}
+			netconf.Ethtool = &sriovtypes.EthtoolConf{Features: features}
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedEthtool.On("Features", podifName).Return(map[string]bool{"rx-lro": false}, nil)
+			mockedEthtool.On("ChangeFeatures", podifName, features).Return(fmt.Errorf("feature rx-lro is fixed: %w", syscall.EOPNOTSUPP))
+			mockedEthtool.On("DriverName", podifName).Return("iavf", nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			sm := sriovManager{nLink: mocked, ethtool: mockedEthtool, utils: mockedPciUtils}
+			journal := NewJournal()
+			err = sm.SetupVF(netconf, podifName, targetNetNS, journal)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("driver iavf of net1 does not support setting features"))
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+			// the other requested features may already be changed, so they must be rolled back
+			Expect(journal.Steps()).To(ContainElement("ethtool features"))
+		})
+		It("Setting VF's per-interface sysctls", func() {
+			var targetNetNS ns.NetNS
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			netconf.Sysctl = map[string]string{
+				"net.ipv6.conf.net1.accept_ra":   "0",
+				"net.ipv4.conf.IFNAME.rp_filter": "2",
+			}
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("SetIfSysctl", "ipv4", podifName, "rp_filter", "2").Return(nil).Once()
+			mockedPciUtils.On("SetIfSysctl", "ipv6", podifName, "accept_ra", "0").Return(nil).Once()
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.SetupVF(netconf, podifName, targetNetNS, NewJournal())
+			Expect(err).NotTo(HaveOccurred())
+			mockedPciUtils.AssertExpectations(t)
+		})
+		It("Rejecting a sysctl of another interface or unknown to the kernel", func() {
+			var targetNetNS ns.NetNS
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", mock.AnythingOfType("string")).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedPciUtils.On("EnableArpAndNdiscNotify", mock.AnythingOfType("string")).Return(nil)
+			mockedPciUtils.On("SetIfSysctl", "ipv4", podifName, "no_such_param", "1").Return(fmt.Errorf("failed to open sysctl: %w", os.ErrNotExist))
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+
+			netconf.Sysctl = map[string]string{"net.ipv4.conf.eth0.rp_filter": "2"}
+			err = sm.SetupVF(netconf, podifName, targetNetNS, NewJournal())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("is not for the pod interface net1"))
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+
+			netconf.Sysctl = map[string]string{"net.ipv4.conf.IFNAME.no_such_param": "1"}
+			err = sm.SetupVF(netconf, podifName, targetNetNS, NewJournal())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unknown sysctl net.ipv4.conf.IFNAME.no_such_param"))
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+		})
+		It("Rejecting an MTU larger than the PF MTU", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mtu := 9000
+			netconf.MTU = &mtu
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", MTU: 1500}}
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "enp175s0f1", MTU: 1500}}
+
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.SetupVF(netconf, podifName, nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("exceeds the mtu 1500 of PF enp175s0f1"))
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+		})
+		It("Rejecting an MTU larger than the VF maximum MTU", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mtu := 9000
+			netconf.MTU = &mtu
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", MTU: 1500}}
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "enp175s0f1", MTU: 9000}}
+
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkGetMaxMTU", fakeLink).Return(2000, nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.SetupVF(netconf, podifName, nil, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("exceeds its maximum mtu 2000"))
+		})
 	})
 
 	Context("Checking ReleaseVF function", func() {
@@ -165,6 +404,117 @@ var _ = Describe("Sriov", func() {
 			Expect(err).NotTo(HaveOccurred())
 			mocked.AssertExpectations(t)
 		})
+		It("Restores the original ethtool settings", func() {
+			var targetNetNS ns.NetNS
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedEthtool := &mocks_utils.EthtoolManager{}
+
+			rx := uint32(512)
+			features := map[string]bool{"rx-gro": true}
+			netconf.OrigVfState.Ethtool = &sriovtypes.EthtoolConf{
+				Features: features,
+				Ring:     &sriovtypes.EthtoolRing{Rx: &rx},
+			}
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", netconf.ContIFNames).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedEthtool.On("ChangeFeatures", "enp175s6", features).Return(nil)
+			mockedEthtool.On("GetRing", "enp175s6").Return(uint32(4096), uint32(1024), nil)
+			mockedEthtool.On("SetRing", "enp175s6", uint32(512), uint32(1024)).Return(nil)
+			sm := sriovManager{nLink: mocked, ethtool: mockedEthtool}
+			err = sm.ReleaseVF(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mockedEthtool.AssertExpectations(t)
+		})
+		It("Restores the original MTU, transmit queue length and flags", func() {
+			var targetNetNS ns.NetNS
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+
+			mtu, txQueueLen := 9000, 5000
+			netconf.MTU = &mtu
+			netconf.TxQueueLen = &txQueueLen
+			netconf.Promisc = "on"
+			netconf.Allmulti = "off"
+			netconf.OrigVfState.MTU = 1500
+			netconf.OrigVfState.TxQueueLen = 1000
+			netconf.OrigVfState.Allmulti = true
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", netconf.ContIFNames).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetMTU", fakeLink, 1500).Return(nil)
+			mocked.On("LinkSetTxQLen", fakeLink, 1000).Return(nil)
+			mocked.On("LinkSetPromiscOff", fakeLink).Return(nil)
+			mocked.On("LinkSetAllmulticastOn", fakeLink).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err = sm.ReleaseVF(netconf, podifName, targetNetNS)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Moves the VF back to the init netns when its attributes can't be restored", func() {
+			var targetNetNS ns.NetNS
+			targetNetNS, err := testutils.NewNS()
+			defer func() {
+				if targetNetNS != nil {
+					targetNetNS.Close()
+				}
+			}()
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedEthtool := &mocks_utils.EthtoolManager{}
+
+			mtu, txQueueLen := 9000, 5000
+			netconf.MTU = &mtu
+			netconf.TxQueueLen = &txQueueLen
+			netconf.OrigVfState.MTU = 1500
+			netconf.OrigVfState.TxQueueLen = 1000
+			rx := uint32(512)
+			features := map[string]bool{"rx-gro": true}
+			netconf.OrigVfState.Ethtool = &sriovtypes.EthtoolConf{
+				Features: features,
+				Ring:     &sriovtypes.EthtoolRing{Rx: &rx},
+			}
+
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", netconf.ContIFNames).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetMTU", fakeLink, 1500).Return(errors.New("invalid argument"))
+			mocked.On("LinkSetTxQLen", fakeLink, 1000).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mockedEthtool.On("ChangeFeatures", "enp175s6", features).Return(errors.New("operation not supported"))
+			mockedEthtool.On("GetRing", "enp175s6").Return(uint32(4096), uint32(1024), nil)
+			mockedEthtool.On("SetRing", "enp175s6", uint32(512), uint32(1024)).Return(nil)
+			sm := sriovManager{nLink: mocked, ethtool: mockedEthtool}
+			err = sm.ReleaseVF(netconf, podifName, targetNetNS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to restore original mtu 1500"))
+			Expect(err.Error()).To(ContainSubstring("failed to restore original features of enp175s6"))
+			mocked.AssertExpectations(t)
+			mockedEthtool.AssertExpectations(t)
+		})
 	})
 	Context("Checking ReleaseVF function - restore config", func() {
 		var (
@@ -279,6 +629,212 @@ var _ = Describe("Sriov", func() {
 			Expect(err).NotTo(HaveOccurred())
 			mocked.AssertExpectations(t)
 		})
+		It("Saves the current VF vlan protocol when vlanProto is configured", func() {
+			netconf.VlanProto = sriovtypes.VlanProto8021ad
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{
+				Index: 1000,
+				Name:  "dummylink",
+				Vfs:   []netlink.VfInfo{{ID: 0}},
+			}}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkGetVfVlanProto", fakeLink, 0).Return(0x8100, nil)
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.FillOriginalVfInfo(netconf)).To(Succeed())
+			Expect(netconf.OrigVfState.VlanProto).To(Equal(0x8100))
+			mocked.AssertExpectations(t)
+		})
+		It("Saves the current VF vlan trunk when vlanTrunk is configured", func() {
+			netconf.VlanTrunk = "100-200"
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedSysfs := &mocks_utils.SriovSysfsManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{
+				Index: 1000,
+				Name:  "dummylink",
+				Vfs:   []netlink.VfInfo{{ID: 0}},
+			}}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedSysfs.On("VfTrunkGet", netconf.Master, 0).Return("300", nil)
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			Expect(sm.FillOriginalVfInfo(netconf)).To(Succeed())
+			Expect(netconf.OrigVfState.VlanTrunk).To(Equal("300"))
+			mocked.AssertExpectations(t)
+			mockedSysfs.AssertExpectations(t)
+		})
+		It("Fails with a capability error when the driver has no vlan trunk support", func() {
+			netconf.VlanTrunk = "100-200"
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedSysfs := &mocks_utils.SriovSysfsManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{
+				Index: 1000,
+				Name:  "dummylink",
+				Vfs:   []netlink.VfInfo{{ID: 0}},
+			}}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedSysfs.On("VfTrunkGet", netconf.Master, 0).Return("", fmt.Errorf("failed to read trunk: %w", os.ErrNotExist))
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			err := sm.FillOriginalVfInfo(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does not support vlanTrunk of vf 0"))
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+		})
+		It("Saves the current VF mirrors when mirrors are configured", func() {
+			target := 1
+			netconf.IngressMirror = &target
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedSysfs := &mocks_utils.SriovSysfsManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{
+				Index: 1000,
+				Name:  "dummylink",
+				Vfs:   []netlink.VfInfo{{ID: 0}},
+			}}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedSysfs.On("VfMirrorGet", netconf.Master, 0, utils.MirrorIngress).Return(utils.MirrorOff, nil)
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			Expect(sm.FillOriginalVfInfo(netconf)).To(Succeed())
+			Expect(netconf.OrigVfState.IngressMirror).To(Equal(utils.MirrorOff))
+			mockedSysfs.AssertExpectations(t)
+		})
+	})
+	Context("Checking ReleaseVFByPCI function", func() {
+		It("Moves the netdev with the VF pci address back to the init netns", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			netconf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0}
+			otherLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "eth0"}}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "net1"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			mocked.On("LinkList").Return([]netlink.Link{otherLink, fakeLink}, nil)
+			mockedPciUtils.On("GetLinkPciAddress", "eth0").Return("", errors.New("not a pci device"))
+			mockedPciUtils.On("GetLinkPciAddress", "net1").Return("0000:af:06.0", nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, "sriov0000af060").Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ReleaseVFByPCI(netconf, targetNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mockedPciUtils.AssertExpectations(t)
+		})
+		It("Finds the virtio netdev of a vDPA device by the name of the device", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			netconf := &sriovtypes.NetConf{Master: "ens2f0", DeviceID: "0000:3b:00.2", VFID: 0,
+				OrigVfState: sriovtypes.VfState{HostIFName: "eth0"},
+				VdpaDevice:  &sriovtypes.VdpaDevice{Name: "vdpa0", Driver: sriovtypes.VdpaDriverVirtio, NetDev: "eth0"}}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "net1"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			mocked.On("LinkList").Return([]netlink.Link{fakeLink}, nil)
+			mockedPciUtils.On("GetLinkPciAddress", "net1").Return("vdpa0", nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, "eth0").Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.ReleaseVFByPCI(netconf, targetNetNS)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+		It("Fails when no netdev has the VF pci address", func() {
+			targetNetNS, err := testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			defer targetNetNS.Close()
+
+			netconf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0}
+			otherLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "eth0"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			mocked.On("LinkList").Return([]netlink.Link{otherLink}, nil)
+			mockedPciUtils.On("GetLinkPciAddress", "eth0").Return("0000:af:06.1", nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err = sm.ReleaseVFByPCI(netconf, targetNetNS)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to find netdev of VF 0000:af:06.0"))
+		})
+	})
+	Context("Checking RestoreVFHostState function", func() {
+		var netconf *sriovtypes.NetConf
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+				MAC:      "d2:fc:22:a7:0d:e8",
+				OrigVfState: sriovtypes.VfState{
+					HostIFName:   "enp175s6",
+					EffectiveMAC: "aa:f3:8d:65:1b:d4",
+				},
+			}
+		})
+		It("Restores the host name and effective MAC of a VF back in the init netns", func() {
+			origMac, err := net.ParseMAC(netconf.OrigVfState.EffectiveMAC)
+			Expect(err).NotTo(HaveOccurred())
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "eth5", HardwareAddr: origMac}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			mockedPciUtils.On("GetVFLinkNamesFromVFID", netconf.Master, netconf.VFID).Return([]string{"eth5"}, nil)
+			mocked.On("LinkByName", "eth5").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, "enp175s6").Return(nil)
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkSetHardwareAddr", fakeLink, origMac).Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.RestoreVFHostState(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+		It("Finds the virtio netdev of a vDPA device back in the init netns", func() {
+			netconf.MAC = ""
+			netconf.VdpaDevice = &sriovtypes.VdpaDevice{Name: "vdpa0", Driver: sriovtypes.VdpaDriverVirtio, NetDev: "enp175s6"}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "eth5"}}
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			mockedPciUtils.On("GetVdpaDevice", netconf.DeviceID).Return(&sriovtypes.VdpaDevice{Name: "vdpa0", Driver: sriovtypes.VdpaDriverVirtio, NetDev: "eth5"}, nil)
+			mocked.On("LinkByName", "eth5").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, "enp175s6").Return(nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.RestoreVFHostState(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mockedPciUtils.AssertExpectations(t)
+		})
+		It("Does nothing while the VF is not back in the init netns", func() {
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+
+			mockedPciUtils.On("GetVFLinkNamesFromVFID", netconf.Master, netconf.VFID).Return(nil, errors.New("no such file or directory"))
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			Expect(sm.RestoreVFHostState(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking ResetVFToDefault function", func() {
+		It("Applies every default setting even if one fails", func() {
+			netconf := &sriovtypes.NetConf{Master: "enp175s0f1", DeviceID: "0000:af:06.0", VFID: 0}
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkSetVfVlanQos", fakeLink, 0, 0, 0).Return(nil)
+			mocked.On("LinkSetVfHardwareAddr", fakeLink, 0, net.HardwareAddr{0, 0, 0, 0, 0, 0}).Return(errors.New("not supported"))
+			mocked.On("LinkSetVfSpoofchk", fakeLink, 0, true).Return(nil)
+			mocked.On("LinkSetVfTrust", fakeLink, 0, false).Return(nil)
+			mocked.On("LinkSetVfRate", fakeLink, 0, 0, 0).Return(nil)
+			mocked.On("LinkSetVfState", fakeLink, 0, uint32(netlink.VF_LINK_STATE_AUTO)).Return(nil)
+			sm := sriovManager{nLink: mocked}
+			err := sm.ResetVFToDefault(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("administrative MAC address"))
+			mocked.AssertExpectations(t)
+		})
 	})
 	Context("Checking ResetVFConfig function - restore config no user params", func() {
 		var (
@@ -309,7 +865,10 @@ var _ = Describe("Sriov", func() {
 	})
 	Context("Checking ResetVFConfig function - restore config with user params", func() {
 		var (
-			netconf *sriovtypes.NetConf
+			netconf     *sriovtypes.NetConf
+			mocked      *mocks_utils.NetlinkManager
+			mockedSysfs *mocks_utils.SriovSysfsManager
+			fakeLink    *utils.FakeLink
 		)
 
 		BeforeEach(func() {
@@ -343,27 +902,605 @@ var _ = Describe("Sriov", func() {
 					LinkState:    2, // disable
 				},
 			}
-		})
-		It("Restores original VF configurations", func() {
+
 			origMac, err := net.ParseMAC(netconf.OrigVfState.AdminMAC)
 			Expect(err).NotTo(HaveOccurred())
-			mocked := &mocks_utils.NetlinkManager{}
-			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
+			mocked = &mocks_utils.NetlinkManager{}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
 				{Mac: origMac},
 			}}}
 
 			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
-			mocked.On("LinkSetVfVlanQos", fakeLink, netconf.VFID, netconf.OrigVfState.Vlan, netconf.OrigVfState.VlanQoS).Return(nil)
 			mocked.On("LinkSetVfSpoofchk", fakeLink, netconf.VFID, netconf.OrigVfState.SpoofChk).Return(nil)
 			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, origMac).Return(nil)
 			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, false).Return(nil)
 			mocked.On("LinkSetVfRate", fakeLink, netconf.VFID, netconf.OrigVfState.MinTxRate, netconf.OrigVfState.MaxTxRate).Return(nil)
 			mocked.On("LinkSetVfState", fakeLink, netconf.VFID, netconf.OrigVfState.LinkState).Return(nil)
+			mockedSysfs = &mocks_utils.SriovSysfsManager{}
+		})
+		It("Restores original VF configurations", func() {
+			mocked.On("LinkSetVfVlanQos", fakeLink, netconf.VFID, netconf.OrigVfState.Vlan, netconf.OrigVfState.VlanQoS).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			err := sm.ResetVFConfig(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Restores the original vlan protocol", func() {
+			netconf.VlanProto = sriovtypes.VlanProto8021ad
+			netconf.OrigVfState.VlanProto = 0x8100
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, netconf.VFID, netconf.OrigVfState.Vlan, netconf.OrigVfState.VlanQoS, 0x8100).Return(nil)
+
+			sm := sriovManager{nLink: mocked}
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mocked.AssertNotCalled(t, "LinkSetVfVlanQos", fakeLink, netconf.VFID, netconf.OrigVfState.Vlan, netconf.OrigVfState.VlanQoS)
+		})
+		It("Restores the original vlan trunk", func() {
+			netconf.Vlan = nil
+			netconf.VlanQoS = nil
+			netconf.VlanTrunk = "100-200"
+			netconf.OrigVfState.VlanTrunk = "300"
+			mockedSysfs.On("VfTrunkRemove", netconf.Master, netconf.VFID, "100-200").Return(nil)
+			mockedSysfs.On("VfTrunkAdd", netconf.Master, netconf.VFID, "300").Return(nil)
+
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mockedSysfs.AssertExpectations(t)
+		})
+		It("Clears the mirrors rather than restoring the original ones", func() {
+			target := 1
+			netconf.Vlan = nil
+			netconf.VlanQoS = nil
+			netconf.EgressMirror = &target
+			netconf.OrigVfState.EgressMirror = 2
+			mockedSysfs.On("VfMirrorRemove", netconf.Master, netconf.VFID, utils.MirrorEgress, 1).Return(nil)
+
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mockedSysfs.AssertExpectations(t)
+			mockedSysfs.AssertNotCalled(t, "VfMirrorAdd", netconf.Master, netconf.VFID, utils.MirrorEgress, 2)
+		})
+	})
+	Context("Checking ApplyVFConfig function", func() {
+		var (
+			netconf     *sriovtypes.NetConf
+			mocked      *mocks_utils.NetlinkManager
+			mockedSysfs *mocks_utils.SriovSysfsManager
+			fakeLink    *utils.FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+			}
+			fakeLink = &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink"}}
+			mocked = &mocks_utils.NetlinkManager{}
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedSysfs = &mocks_utils.SriovSysfsManager{}
+		})
+		It("Sets the vlan protocol along with the vlan and restores it on rollback", func() {
+			vlan := 100
+			netconf.Vlan = &vlan
+			netconf.VlanProto = sriovtypes.VlanProto8021ad
+			netconf.OrigVfState.VlanProto = 0x8100
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, 0, 100, 0, 0x88a8).Return(nil)
+
+			journal := NewJournal()
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			Expect(sm.ApplyVFConfig(netconf, journal)).To(Succeed())
+			Expect(journal.Steps()).To(Equal([]string{"vlan"}))
+
+			mocked.On("LinkSetVfVlanQosProto", fakeLink, 0, 0, 0, 0x8100).Return(nil)
+			Expect(journal.Rollback()).To(Succeed())
+			mocked.AssertExpectations(t)
+		})
+		It("Replaces the original vlan trunk and restores it on rollback", func() {
+			netconf.VlanTrunk = "100-200"
+			netconf.OrigVfState.VlanTrunk = "300"
+			mocked.On("LinkSetVfVlan", fakeLink, 0, 0).Return(nil)
+			mockedSysfs.On("VfTrunkRemove", netconf.Master, 0, "300").Return(nil)
+			mockedSysfs.On("VfTrunkAdd", netconf.Master, 0, "100-200").Return(nil)
+
+			journal := NewJournal()
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			Expect(sm.ApplyVFConfig(netconf, journal)).To(Succeed())
+			Expect(journal.Steps()).To(Equal([]string{"vlan", "vlan_trunk"}))
+
+			mockedSysfs.On("VfTrunkRemove", netconf.Master, 0, "100-200").Return(nil)
+			mockedSysfs.On("VfTrunkAdd", netconf.Master, 0, "300").Return(nil)
+			Expect(journal.Rollback()).To(Succeed())
+			mockedSysfs.AssertExpectations(t)
+		})
+		It("Mirrors the VF and restores the original mirrors on rollback", func() {
+			target := 1
+			netconf.IngressMirror = &target
+			netconf.EgressMirror = &target
+			netconf.OrigVfState.IngressMirror = utils.MirrorOff
+			netconf.OrigVfState.EgressMirror = 2
+			mocked.On("LinkSetVfVlan", fakeLink, 0, 0).Return(nil)
+			mockedSysfs.On("VfMirrorAdd", netconf.Master, 0, utils.MirrorIngress, 1).Return(nil)
+			mockedSysfs.On("VfMirrorRemove", netconf.Master, 0, utils.MirrorEgress, 2).Return(nil)
+			mockedSysfs.On("VfMirrorAdd", netconf.Master, 0, utils.MirrorEgress, 1).Return(nil)
+
+			journal := NewJournal()
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			Expect(sm.ApplyVFConfig(netconf, journal)).To(Succeed())
+			Expect(journal.Steps()).To(Equal([]string{"vlan", "ingress_mirror", "egress_mirror"}))
+
+			mockedSysfs.On("VfMirrorRemove", netconf.Master, 0, utils.MirrorIngress, 1).Return(nil)
+			mockedSysfs.On("VfMirrorRemove", netconf.Master, 0, utils.MirrorEgress, 1).Return(nil)
+			mockedSysfs.On("VfMirrorAdd", netconf.Master, 0, utils.MirrorEgress, 2).Return(nil)
+			Expect(journal.Rollback()).To(Succeed())
+			mockedSysfs.AssertExpectations(t)
+		})
+	})
+	Context("Checking switchdev mode", func() {
+		var (
+			netconf *sriovtypes.NetConf
+			port    *netlink.DevlinkPort
+			origMac net.HardwareAddr
+		)
+
+		BeforeEach(func() {
+			netconf = &sriovtypes.NetConf{
+				Master:   "enp175s0f1",
+				DeviceID: "0000:af:06.0",
+				VFID:     0,
+			}
+			origMac, _ = net.ParseMAC("00:00:00:00:00:00")
+			port = &netlink.DevlinkPort{
+				BusName:       "pci",
+				DeviceName:    "0000:af:00.1",
+				PortIndex:     65537,
+				NetdeviceName: "enp175s0f1_0",
+				PortFlavour:   nl.DEVLINK_PORT_FLAVOUR_PCI_VF,
+				Fn:            &netlink.DevlinkPortFn{HwAddr: origMac},
+			}
+		})
+		It("Detects the representor of a VF of a switchdev PF", func() {
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetPfPciAddress", "enp175s0f1").Return("0000:af:00.1", nil)
+			mockedPciUtils.On("GetVfRepresentor", "enp175s0f1", 0).Return("enp175s0f1_0", nil)
+			mockedDevlink.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(&netlink.DevlinkDevice{
+				Attrs: netlink.DevlinkDevAttrs{Eswitch: netlink.DevlinkDevEswitchAttr{Mode: "switchdev"}},
+			}, nil)
+			sm := sriovManager{devlink: mockedDevlink, utils: mockedPciUtils}
+			Expect(sm.DetectSwitchdev(netconf)).To(Succeed())
+			Expect(netconf.Representor).To(Equal("enp175s0f1_0"))
+		})
+		It("Detects the representor of a Scalable Function", func() {
+			sfNum := 4
+			netconf.DeviceID = "mlx5_core.sf.4"
+			netconf.SFNum = &sfNum
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetPfPciAddress", "enp175s0f1").Return("0000:af:00.1", nil)
+			mockedPciUtils.On("GetSfRepresentor", "enp175s0f1", 4).Return("enp175s0f1pf1sf4", nil)
+			mockedDevlink.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(&netlink.DevlinkDevice{
+				Attrs: netlink.DevlinkDevAttrs{Eswitch: netlink.DevlinkDevEswitchAttr{Mode: "switchdev"}},
+			}, nil)
+			sm := sriovManager{devlink: mockedDevlink, utils: mockedPciUtils}
+			Expect(sm.DetectSwitchdev(netconf)).To(Succeed())
+			Expect(netconf.Representor).To(Equal("enp175s0f1pf1sf4"))
+		})
+		It("Rejects a Scalable Function of a PF in legacy mode", func() {
+			sfNum := 4
+			netconf.DeviceID = "mlx5_core.sf.4"
+			netconf.SFNum = &sfNum
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetPfPciAddress", "enp175s0f1").Return("0000:af:00.1", nil)
+			mockedDevlink.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(&netlink.DevlinkDevice{
+				Attrs: netlink.DevlinkDevAttrs{Eswitch: netlink.DevlinkDevEswitchAttr{Mode: "legacy"}},
+			}, nil)
+			sm := sriovManager{devlink: mockedDevlink, utils: mockedPciUtils}
+			err := sm.DetectSwitchdev(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+		})
+		It("Keeps the legacy mode when the PF has no devlink device", func() {
+			netconf.Representor = "stale"
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			mockedPciUtils.On("GetPfPciAddress", "enp175s0f1").Return("0000:af:00.1", nil)
+			mockedDevlink.On("DevLinkGetDeviceByName", "pci", "0000:af:00.1").Return(nil, syscall.ENODEV)
+			sm := sriovManager{devlink: mockedDevlink, utils: mockedPciUtils}
+			Expect(sm.DetectSwitchdev(netconf)).To(Succeed())
+			Expect(netconf.Representor).To(BeEmpty())
+			mockedPciUtils.AssertNotCalled(t, "GetVfRepresentor", "enp175s0f1", 0)
+		})
+		It("Sets the MAC address through the port function and brings the representor up", func() {
+			netconf.Representor = "enp175s0f1_0"
+			netconf.MAC = "60:00:00:00:00:01"
+			netconf.OrigVfState.AdminMAC = origMac.String()
+			newMac, err := net.ParseMAC(netconf.MAC)
+			Expect(err).NotTo(HaveOccurred())
+			repLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0"}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mocked.On("LinkSetUp", repLink).Return(nil)
+			mockedDevlink.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			mockedDevlink.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(65537), netlink.DevlinkPortFnSetAttrs{
+				FnAttrs: netlink.DevlinkPortFn{HwAddr: newMac}, HwAddrValid: true,
+			}).Return(nil)
+
+			journal := NewJournal()
+			sm := sriovManager{nLink: mocked, devlink: mockedDevlink}
+			Expect(sm.ApplyVFConfig(netconf, journal)).To(Succeed())
+			Expect(journal.Steps()).To(Equal([]string{"mac", "representor up"}))
+			mocked.AssertNotCalled(t, "LinkSetVfVlan", mock.Anything, mock.Anything, mock.Anything)
+
+			mocked.On("LinkSetDown", repLink).Return(nil)
+			mockedDevlink.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(65537), netlink.DevlinkPortFnSetAttrs{
+				FnAttrs: netlink.DevlinkPortFn{HwAddr: origMac}, HwAddrValid: true,
+			}).Return(nil)
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mockedDevlink.AssertExpectations(t)
+		})
+		It("Rejects the settings that only apply in legacy mode", func() {
+			vlan := 100
+			netconf.Representor = "enp175s0f1_0"
+			netconf.Vlan = &vlan
+			netconf.Trust = "on"
+			sm := sriovManager{}
+			err := sm.ApplyVFConfig(netconf, NewJournal())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("vlan, trust not supported when PF enp175s0f1 is in switchdev mode"))
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+		})
+		It("Sets the port function capabilities and restores them on rollback", func() {
+			netconf.Representor = "enp175s0f1_0"
+			netconf.PortFunction = &sriovtypes.PortFunction{Roce: "off", Migratable: "on"}
+			netconf.OrigVfState.PortFnCaps = utils.PortFnCapRoce
+			netconf.OrigVfState.RepresentorUp = true
+			selector := utils.PortFnCapRoce | utils.PortFnCapMigratable
+
+			repLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0", Flags: net.FlagUp}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mockedDevlink.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			mockedDevlink.On("DevlinkPortFnCapsSet", "pci", "0000:af:00.1", uint32(65537), utils.PortFnCapMigratable, selector).Return(nil)
+
+			journal := NewJournal()
+			sm := sriovManager{nLink: mocked, devlink: mockedDevlink}
+			Expect(sm.ApplyVFConfig(netconf, journal)).To(Succeed())
+			Expect(journal.Steps()).To(Equal([]string{"port function caps"}))
+
+			mockedDevlink.On("DevlinkPortFnCapsSet", "pci", "0000:af:00.1", uint32(65537), utils.PortFnCapRoce, selector).Return(nil)
+			Expect(journal.Rollback()).To(Succeed())
+			mockedDevlink.AssertExpectations(t)
+		})
+		It("Resets the port function and the representor to their defaults without a cache", func() {
+			netconf.Representor = "enp175s0f1_0"
+			repLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0", Flags: net.FlagUp}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mocked.On("LinkSetDown", repLink).Return(nil)
+			mockedDevlink.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			mockedDevlink.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(65537), netlink.DevlinkPortFnSetAttrs{
+				FnAttrs: netlink.DevlinkPortFn{HwAddr: origMac}, HwAddrValid: true,
+			}).Return(nil)
+			mockedDevlink.On("DevlinkPortFnCapsGet", "pci", "0000:af:00.1", uint32(65537)).Return(utils.PortFnCapMigratable|utils.PortFnCapIpsecCrypto, nil)
+			mockedDevlink.On("DevlinkPortFnCapsSet", "pci", "0000:af:00.1", uint32(65537), utils.PortFnCapRoce, utils.PortFnCapsAll).Return(nil)
+
+			sm := sriovManager{nLink: mocked, devlink: mockedDevlink}
+			Expect(sm.ResetVFToDefault(netconf)).To(Succeed())
+			mocked.AssertExpectations(t)
+			mockedDevlink.AssertExpectations(t)
+		})
+		It("Leaves the port function capabilities alone when they are the defaults", func() {
+			netconf.Representor = "enp175s0f1_0"
+			repLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0"}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mocked.On("LinkSetDown", repLink).Return(nil)
+			mockedDevlink.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			mockedDevlink.On("DevlinkPortFnSet", "pci", "0000:af:00.1", uint32(65537), mock.Anything).Return(nil)
+			mockedDevlink.On("DevlinkPortFnCapsGet", "pci", "0000:af:00.1", uint32(65537)).Return(utils.PortFnCapRoce, nil)
+
+			sm := sriovManager{nLink: mocked, devlink: mockedDevlink}
+			Expect(sm.ResetVFToDefault(netconf)).To(Succeed())
+			mockedDevlink.AssertNotCalled(t, "DevlinkPortFnCapsSet", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+		It("Rejects port function attributes when the PF is in legacy mode", func() {
+			netconf.PortFunction = &sriovtypes.PortFunction{Roce: "on"}
+			sm := sriovManager{}
+			err := sm.ApplyVFConfig(netconf, NewJournal())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("portFunction requires the eswitch of PF enp175s0f1 to be in switchdev mode"))
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+		})
+		It("Saves the port function MAC address, capabilities and the representor state", func() {
+			netconf.Representor = "enp175s0f1_0"
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{{ID: 0}}}}
+			repLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0", Flags: net.FlagUp}}
+			port.Fn.HwAddr, _ = net.ParseMAC("6e:16:06:0e:b7:e9")
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mockedDevlink.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			mockedDevlink.On("DevlinkPortFnCapsGet", "pci", "0000:af:00.1", uint32(65537)).Return(utils.PortFnCapRoce|utils.PortFnCapIpsecCrypto, nil)
+			netconf.PortFunction = &sriovtypes.PortFunction{Roce: "off"}
+			sm := sriovManager{nLink: mocked, devlink: mockedDevlink}
+			Expect(sm.FillOriginalVfInfo(netconf)).To(Succeed())
+			Expect(netconf.OrigVfState.AdminMAC).To(Equal("6e:16:06:0e:b7:e9"))
+			Expect(netconf.OrigVfState.RepresentorUp).To(BeTrue())
+			Expect(netconf.OrigVfState.PortFnCaps).To(Equal(utils.PortFnCapRoce | utils.PortFnCapIpsecCrypto))
+		})
+		It("Saves the state of a Scalable Function without reading the VF info of the PF", func() {
+			sfNum := 4
+			netconf.DeviceID = "mlx5_core.sf.4"
+			netconf.SFNum = &sfNum
+			netconf.Representor = "enp175s0f1pf1sf4"
+			port.NetdeviceName = "enp175s0f1pf1sf4"
+			port.PortFlavour = nl.DEVLINK_PORT_FLAVOUR_PCI_SF
+			port.Fn.HwAddr, _ = net.ParseMAC("6e:16:06:0e:b7:e9")
+			repLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1002, Name: "enp175s0f1pf1sf4"}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1pf1sf4").Return(repLink, nil)
+			mockedDevlink.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			sm := sriovManager{nLink: mocked, devlink: mockedDevlink}
+			Expect(sm.FillOriginalVfInfo(netconf)).To(Succeed())
+			Expect(netconf.OrigVfState.AdminMAC).To(Equal("6e:16:06:0e:b7:e9"))
+			Expect(netconf.OrigVfState.RepresentorUp).To(BeFalse())
+			mocked.AssertNotCalled(t, "LinkByName", "enp175s0f1")
+
+			netconf.MAC = "6e:16:06:0e:b7:e9"
+			Expect(sm.CheckVFConfig(netconf)).To(Succeed())
+			mocked.AssertNotCalled(t, "LinkByName", "enp175s0f1")
+		})
+		It("Caches the rate leaf of the VF", func() {
+			netconf.Representor = "enp175s0f1_0"
+			netconf.RateGroup = "tenant_a"
+			pfLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "enp175s0f1", Vfs: []netlink.VfInfo{{ID: 0}}}}
+			repLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0"}}
+			leaf := &sriovtypes.DevlinkRate{BusName: "pci", DeviceName: "0000:af:00.1", Leaf: true, PortIndex: 65537, TxMax: 1000}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1").Return(pfLink, nil)
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mockedDevlink.On("DevLinkGetAllPortList").Return([]*netlink.DevlinkPort{port}, nil)
+			mockedDevlink.On("DevlinkRateList", "pci", "0000:af:00.1").Return([]*sriovtypes.DevlinkRate{
+				{BusName: "pci", DeviceName: "0000:af:00.1", Leaf: true, PortIndex: 65538},
+				leaf,
+			}, nil)
+			sm := sriovManager{nLink: mocked, devlink: mockedDevlink}
+			Expect(sm.FillOriginalVfInfo(netconf)).To(Succeed())
+			Expect(netconf.OrigVfState.RateLeaf).To(Equal(leaf))
+		})
+		It("Creates the rate group of the VF and deletes it along with its last member", func() {
+			txShare, txMax := 100, 1000
+			netconf.Representor = "enp175s0f1_0"
+			netconf.RateGroup = "tenant_a"
+			netconf.TxShare = &txShare
+			netconf.TxMax = &txMax
+			netconf.OrigVfState.RepresentorUp = true
+			netconf.OrigVfState.RateLeaf = &sriovtypes.DevlinkRate{BusName: "pci", DeviceName: "0000:af:00.1", Leaf: true, PortIndex: 65537}
+			repLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0", Flags: net.FlagUp}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mockedDevlink.On("DevlinkRateList", "pci", "0000:af:00.1").Return([]*sriovtypes.DevlinkRate{netconf.OrigVfState.RateLeaf}, nil)
+			mockedDevlink.On("DevlinkRateNodeNew", "pci", "0000:af:00.1", "tenant_a").Return(nil)
+			mockedDevlink.On("DevlinkRateLeafSet", "pci", "0000:af:00.1", uint32(65537), uint64(12500000), uint64(125000000), "tenant_a").Return(nil)
+
+			journal := NewJournal()
+			sm := sriovManager{nLink: mocked, devlink: mockedDevlink}
+			Expect(sm.ApplyVFConfig(netconf, journal)).To(Succeed())
+			Expect(journal.Steps()).To(Equal([]string{"rate group", "rate"}))
+
+			mockedDevlink.On("DevlinkRateLeafSet", "pci", "0000:af:00.1", uint32(65537), uint64(0), uint64(0), "").Return(nil)
+			mockedDevlink.On("DevlinkRateNodeDel", "pci", "0000:af:00.1", "tenant_a").Return(nil)
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mockedDevlink.AssertExpectations(t)
+		})
+		It("Keeps the rate group while it has other members", func() {
+			netconf.Representor = "enp175s0f1_0"
+			netconf.RateGroup = "tenant_a"
+			netconf.OrigVfState.RepresentorUp = true
+			netconf.OrigVfState.RateLeaf = &sriovtypes.DevlinkRate{BusName: "pci", DeviceName: "0000:af:00.1", Leaf: true, PortIndex: 65537, TxMax: 1000}
+			repLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "enp175s0f1_0", Flags: net.FlagUp}}
+
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedDevlink := &mocks_utils.DevlinkManager{}
+			mocked.On("LinkByName", "enp175s0f1_0").Return(repLink, nil)
+			mockedDevlink.On("DevlinkRateList", "pci", "0000:af:00.1").Return([]*sriovtypes.DevlinkRate{
+				{BusName: "pci", DeviceName: "0000:af:00.1", NodeName: "tenant_a"},
+				{BusName: "pci", DeviceName: "0000:af:00.1", Leaf: true, PortIndex: 65538, Parent: "tenant_a"},
+				netconf.OrigVfState.RateLeaf,
+			}, nil)
+			mockedDevlink.On("DevlinkRateLeafSet", "pci", "0000:af:00.1", uint32(65537), uint64(0), uint64(1000), "tenant_a").Return(nil)
+
+			journal := NewJournal()
+			sm := sriovManager{nLink: mocked, devlink: mockedDevlink}
+			Expect(sm.ApplyVFConfig(netconf, journal)).To(Succeed())
+			Expect(journal.Steps()).To(Equal([]string{"rate"}))
+
+			mockedDevlink.On("DevlinkRateLeafSet", "pci", "0000:af:00.1", uint32(65537), uint64(0), uint64(1000), "").Return(nil)
+			Expect(sm.ResetVFConfig(netconf)).To(Succeed())
+			mockedDevlink.AssertExpectations(t)
+			mockedDevlink.AssertNotCalled(t, "DevlinkRateNodeNew", mock.Anything, mock.Anything, mock.Anything)
+			mockedDevlink.AssertNotCalled(t, "DevlinkRateNodeDel", mock.Anything, mock.Anything, mock.Anything)
+		})
+		It("Rejects rate settings when the PF is in legacy mode", func() {
+			txMax := 1000
+			netconf.RateGroup = "tenant_a"
+			netconf.TxMax = &txMax
+			sm := sriovManager{}
+			err := sm.ApplyVFConfig(netconf, NewJournal())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("rateGroup, txShare/txMax requires the eswitch of PF enp175s0f1 to be in switchdev mode"))
+			Expect(srioverrors.ToCNI(err).Code).To(Equal(uint(cnitypes.ErrInvalidNetworkConfig)))
+		})
+	})
+	Context("Checking CheckVFConfig function", func() {
+		var (
+			netconf *sriovtypes.NetConf
+		)
+
+		BeforeEach(func() {
+			vlan := 100
+			maxTxRate := 4000
 
+			netconf = &sriovtypes.NetConf{
+				Master:    "enp175s0f1",
+				DeviceID:  "0000:af:06.0",
+				VFID:      0,
+				MAC:       "d2:fc:22:a7:0d:e8",
+				Vlan:      &vlan,
+				MaxTxRate: &maxTxRate,
+				SpoofChk:  "on",
+				Trust:     "on",
+				LinkState: "enable",
+			}
+		})
+		It("Succeeds when the VF matches the requested configuration", func() {
+			mac, err := net.ParseMAC(netconf.MAC)
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: mac, Vlan: 100, MaxTxRate: 4000, Spoofchk: true, Trust: 1, LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
 			sm := sriovManager{nLink: mocked}
-			err = sm.ResetVFConfig(netconf)
+			err = sm.CheckVFConfig(netconf)
 			Expect(err).NotTo(HaveOccurred())
 			mocked.AssertExpectations(t)
 		})
+		It("Names every mismatched attribute", func() {
+			mac, err := net.ParseMAC("aa:f3:8d:65:1b:d4")
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: mac, Vlan: 0, MaxTxRate: 4000, Spoofchk: true, Trust: 0, LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			sm := sriovManager{nLink: mocked}
+			err = sm.CheckVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mac: expected d2:fc:22:a7:0d:e8, found aa:f3:8d:65:1b:d4"))
+			Expect(err.Error()).To(ContainSubstring("vlan: expected 100, found 0"))
+			Expect(err.Error()).To(ContainSubstring("trust: expected on, found off"))
+			Expect(err.Error()).NotTo(ContainSubstring("spoofchk"))
+		})
+		It("Reports a VF whose vlan protocol has changed", func() {
+			netconf.VlanProto = sriovtypes.VlanProto8021ad
+			mac, err := net.ParseMAC(netconf.MAC)
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: mac, Vlan: 100, MaxTxRate: 4000, Spoofchk: true, Trust: 1, LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mocked.On("LinkGetVfVlanProto", fakeLink, netconf.VFID).Return(0x8100, nil)
+			sm := sriovManager{nLink: mocked}
+			err = sm.CheckVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("vlanProto: expected 802.1ad, found 802.1q"))
+			mocked.AssertExpectations(t)
+		})
+		It("Compares the vlan trunk of the VF by VLAN", func() {
+			netconf.Vlan = nil
+			netconf.VlanTrunk = "100-102,200"
+			mac, err := net.ParseMAC(netconf.MAC)
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedSysfs := &mocks_utils.SriovSysfsManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: mac, MaxTxRate: 4000, Spoofchk: true, Trust: 1, LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedSysfs.On("VfTrunkGet", netconf.Master, netconf.VFID).Return("100,101,102,200", nil).Once()
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			Expect(sm.CheckVFConfig(netconf)).To(Succeed())
+
+			mockedSysfs.On("VfTrunkGet", netconf.Master, netconf.VFID).Return("100-102", nil).Once()
+			err = sm.CheckVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("vlanTrunk: expected 100-102,200, found 100-102"))
+			mockedSysfs.AssertExpectations(t)
+		})
+		It("Reports a VF whose mirrors have changed", func() {
+			target := 1
+			netconf.IngressMirror = &target
+			netconf.EgressMirror = &target
+			mac, err := net.ParseMAC(netconf.MAC)
+			Expect(err).NotTo(HaveOccurred())
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedSysfs := &mocks_utils.SriovSysfsManager{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
+				{ID: 0, Mac: mac, Vlan: 100, MaxTxRate: 4000, Spoofchk: true, Trust: 1, LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedSysfs.On("VfMirrorGet", netconf.Master, netconf.VFID, utils.MirrorIngress).Return(1, nil)
+			mockedSysfs.On("VfMirrorGet", netconf.Master, netconf.VFID, utils.MirrorEgress).Return(utils.MirrorOff, nil)
+			sm := sriovManager{nLink: mocked, sysfs: mockedSysfs}
+			err = sm.CheckVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("egressMirror: expected 1, found off"))
+			Expect(err.Error()).NotTo(ContainSubstring("ingressMirror"))
+			mockedSysfs.AssertExpectations(t)
+		})
+		It("Reports a DPDK VF that is no longer bound to a dpdk driver", func() {
+			netconf.DPDKMode = true
+			netconf.MAC = ""
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
+				{ID: 0, Vlan: 100, MaxTxRate: 4000, Spoofchk: true, Trust: 1, LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedPciUtils.On("HasDpdkDriver", netconf.DeviceID).Return(false, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.CheckVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no longer bound to a dpdk driver"))
+			mockedPciUtils.AssertExpectations(t)
+		})
+		It("Reports a vDPA device bound to another driver", func() {
+			netconf.DPDKMode = true
+			netconf.MAC = ""
+			netconf.VdpaDevice = &sriovtypes.VdpaDevice{Name: "vdpa1", Driver: sriovtypes.VdpaDriverVhost, Path: "/dev/vhost-vdpa-1"}
+			mocked := &mocks_utils.NetlinkManager{}
+			mockedPciUtils := &mocks.PciUtils{}
+			fakeLink := &utils.FakeLink{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "dummylink", Vfs: []netlink.VfInfo{
+				{ID: 0, Vlan: 100, MaxTxRate: 4000, Spoofchk: true, Trust: 1, LinkState: netlink.VF_LINK_STATE_ENABLE},
+			}}}
+
+			mocked.On("LinkByName", netconf.Master).Return(fakeLink, nil)
+			mockedPciUtils.On("GetVdpaDevice", netconf.DeviceID).Return(&sriovtypes.VdpaDevice{Name: "vdpa1", Driver: sriovtypes.VdpaDriverVirtio}, nil)
+			sm := sriovManager{nLink: mocked, utils: mockedPciUtils}
+			err := sm.CheckVFConfig(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("vdpa driver: expected vhost_vdpa, found virtio_vdpa"))
+			mockedPciUtils.AssertExpectations(t)
+		})
 	})
 })
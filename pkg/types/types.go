@@ -1,22 +1,115 @@
 package types
 
 import (
+	"encoding/json"
+
 	"github.com/containernetworking/cni/pkg/types"
 	"github.com/vishvananda/netlink"
 )
 
+const (
+	// VlanProto8021q is the vlanProto value of 802.1Q (C-tag) VLANs, the default
+	VlanProto8021q = "802.1q"
+	// VlanProto8021ad is the vlanProto value of 802.1ad (S-tag) VLANs
+	VlanProto8021ad = "802.1ad"
+)
+
 // VfState represents the state of the VF
 type VfState struct {
-	HostIFName   string
-	SpoofChk     bool
-	Trust        bool
-	AdminMAC     string
-	EffectiveMAC string
-	Vlan         int
-	VlanQoS      int
-	MinTxRate    int
-	MaxTxRate    int
-	LinkState    uint32
+	HostIFName    string
+	SpoofChk      bool
+	Trust         bool
+	AdminMAC      string
+	EffectiveMAC  string
+	Vlan          int
+	VlanQoS       int
+	VlanProto     int    // Only filled when vlanProto is configured
+	VlanTrunk     string // Only filled when vlanTrunk is configured
+	IngressMirror int    // Only filled when ingressMirror is configured, -1 when not mirrored
+	EgressMirror  int    // Only filled when egressMirror is configured, -1 when not mirrored
+	MinTxRate     int
+	MaxTxRate     int
+	LinkState     uint32
+	MTU           int
+	TxQueueLen    int
+	Promisc       bool
+	Allmulti      bool
+	Ethtool       *EthtoolConf // Original values of the ethtool settings configured in NetConf
+	RepresentorUp bool         // Only filled in switchdev mode
+	PortFnCaps    uint32       // Only filled when portFunction capabilities are configured
+	RateLeaf      *DevlinkRate // Only filled when rateGroup, txShare or txMax is configured
+}
+
+// DevlinkRate is a devlink rate object, either the leaf of a devlink port or a node grouping leaves and nodes
+type DevlinkRate struct {
+	BusName    string
+	DeviceName string
+	Leaf       bool
+	PortIndex  uint32 // devlink port of a leaf
+	NodeName   string // name of a node
+	TxShare    uint64 // bytes per second
+	TxMax      uint64 // bytes per second
+	Parent     string // name of the parent node, empty if none
+}
+
+// vDPA bus drivers a vDPA device can be bound to
+const (
+	VdpaDriverVirtio = "virtio_vdpa"
+	VdpaDriverVhost  = "vhost_vdpa"
+)
+
+// VdpaDevice is a vDPA device created on a VF, e.g. with `vdpa dev add`
+type VdpaDevice struct {
+	Name   string `json:"name"`             // name of the device on the vdpa bus, e.g. vdpa0
+	Driver string `json:"driver"`           // virtio_vdpa|vhost_vdpa, empty if not bound
+	NetDev string `json:"netDev,omitempty"` // virtio netdev, virtio_vdpa only
+	Path   string `json:"path,omitempty"`   // vhost-vdpa character device, vhost_vdpa only
+}
+
+// DeviceInfo is the device information of an attachment, as defined by the device-info specification of the
+// Network Plumbing Working Group
+type DeviceInfo struct {
+	Type    string          `json:"type"`
+	Version string          `json:"version"`
+	Vdpa    *VdpaDeviceInfo `json:"vdpa,omitempty"`
+}
+
+// VdpaDeviceInfo is the device-info of a vDPA device
+type VdpaDeviceInfo struct {
+	ParentDevice string `json:"parent-device,omitempty"` // name of the vDPA device
+	Driver       string `json:"driver,omitempty"`        // vhost|virtio
+	Path         string `json:"path,omitempty"`          // vhost-vdpa character device
+	PciAddress   string `json:"pci-address,omitempty"`
+	PfPciAddress string `json:"pf-pci-address,omitempty"`
+}
+
+// PortFunction holds the devlink port function attributes of a VF whose PF is in switchdev mode
+type PortFunction struct {
+	HwAddr      string `json:"hwAddr,omitempty"`      // same as mac, which it is merged into
+	Roce        string `json:"roce,omitempty"`        // on|off
+	Migratable  string `json:"migratable,omitempty"`  // on|off
+	IpsecCrypto string `json:"ipsecCrypto,omitempty"` // on|off
+}
+
+// EthtoolConf holds the ethtool settings of the pod interface
+type EthtoolConf struct {
+	Features map[string]bool  `json:"features,omitempty"` // by kernel feature name, e.g. rx-gro, tx-tcp-segmentation
+	Ring     *EthtoolRing     `json:"ring,omitempty"`
+	Channels *EthtoolChannels `json:"channels,omitempty"`
+}
+
+// EthtoolRing holds the ring sizes of the pod interface, those not set are left unchanged
+type EthtoolRing struct {
+	Rx *uint32 `json:"rx,omitempty"`
+	Tx *uint32 `json:"tx,omitempty"`
+}
+
+// EthtoolChannels holds the channel counts of the pod interface, those not set are left unchanged
+type EthtoolChannels struct {
+	Rx       *uint32 `json:"rx,omitempty"`
+	Tx       *uint32 `json:"tx,omitempty"`
+	Other    *uint32 `json:"other,omitempty"`
+	Combined *uint32 `json:"combined,omitempty"`
 }
 
 // FillFromVfInfo - Fill attributes according to the provided netlink.VfInfo struct
@@ -34,21 +127,53 @@ func (vs *VfState) FillFromVfInfo(info *netlink.VfInfo) {
 // NetConf extends types.NetConf for sriov-cni
 type NetConf struct {
 	types.NetConf
-	OrigVfState   VfState // Stores the original VF state as it was prior to any operations done during cmdAdd flow
-	DPDKMode      bool    `json:"-"`
+	CacheVersion  int             `json:"cacheVersion,omitempty"` // Version of the cache format, set when the NetConf is cached
+	CachedResult  json.RawMessage `json:"cachedResult,omitempty"` // CNI result of ADD, set when the NetConf is cached
+	Owner         *PCIOwner       `json:"owner,omitempty"`        // attachment the VF is allocated to, set when the NetConf is cached
+	OrigVfState   VfState         // Stores the original VF state as it was prior to any operations done during cmdAdd flow
+	Representor   string          `json:"representor,omitempty"` // VF representor netdev, set when the PF eswitch is in switchdev mode
+	VdpaDevice    *VdpaDevice     `json:"vdpaDevice,omitempty"`  // set when the VF is used through a vDPA device
+	DPDKMode      bool            `json:"-"`
 	Master        string
 	MAC           string
 	Vlan          *int   `json:"vlan"`
 	VlanQoS       *int   `json:"vlanQoS"`
-	DeviceID      string `json:"deviceID"` // PCI address of a VF in valid sysfs format
+	VlanProto     string `json:"vlanProto,omitempty"`     // 802.1q|802.1ad
+	VlanTrunk     string `json:"vlanTrunk,omitempty"`     // VLAN IDs and ranges, e.g. 100-200,300
+	IngressMirror *int   `json:"ingressMirror,omitempty"` // ID of the VF the ingress traffic is mirrored to
+	EgressMirror  *int   `json:"egressMirror,omitempty"`  // ID of the VF the egress traffic is mirrored to
+	DeviceID      string `json:"deviceID"`                // PCI address of a VF in valid sysfs format, or auxiliary device name of an SF
 	VFID          int
-	ContIFNames   string // VF names after in the container; used during deletion
-	MinTxRate     *int   `json:"min_tx_rate"`          // Mbps, 0 = disable rate limiting
-	MaxTxRate     *int   `json:"max_tx_rate"`          // Mbps, 0 = disable rate limiting
-	SpoofChk      string `json:"spoofchk,omitempty"`   // on|off
-	Trust         string `json:"trust,omitempty"`      // on|off
-	LinkState     string `json:"link_state,omitempty"` // auto|enable|disable
+	SFNum         *int              `json:"sfNum,omitempty"` // SF number, set when DeviceID is a Scalable Function rather than a VF
+	ContIFNames   string            // VF names after in the container; used during deletion
+	MinTxRate     *int              `json:"min_tx_rate"`          // Mbps, 0 = disable rate limiting
+	MaxTxRate     *int              `json:"max_tx_rate"`          // Mbps, 0 = disable rate limiting
+	SpoofChk      string            `json:"spoofchk,omitempty"`   // on|off
+	Trust         string            `json:"trust,omitempty"`      // on|off
+	LinkState     string            `json:"link_state,omitempty"` // auto|enable|disable
+	MTU           *int              `json:"mtu,omitempty"`        // MTU of the pod interface
+	TxQueueLen    *int              `json:"txQueueLen,omitempty"` // transmit queue length of the pod interface
+	Promisc       string            `json:"promisc,omitempty"`    // on|off
+	Allmulti      string            `json:"allmulti,omitempty"`   // on|off
+	Ethtool       *EthtoolConf      `json:"ethtool,omitempty"`
+	Sysctl        map[string]string `json:"sysctl,omitempty"` // per-interface sysctls of the pod interface
+	PortFunction  *PortFunction     `json:"portFunction,omitempty"`
+	RateGroup     string            `json:"rateGroup,omitempty"`         // devlink rate node the VF is attached to
+	TxShare       *int              `json:"txShare,omitempty"`           // Mbps, guaranteed rate of the devlink rate leaf of the VF
+	TxMax         *int              `json:"txMax,omitempty"`             // Mbps, maximum rate of the devlink rate leaf of the VF, 0 = unlimited
+	LogLevel      string            `json:"logLevel,omitempty"`          // error|warning|info|debug
+	LogFile       string            `json:"logFile,omitempty"`           // path of the log file, stderr is used when not set
+	DevInfoFile   string            `json:"cniDeviceInfoFile,omitempty"` // path of the device-info file of the attachment, passed by Multus
 	RuntimeConfig struct {
 		Mac string `json:"mac,omitempty"`
 	} `json:"runtimeConfig,omitempty"`
 }
+
+// PCIOwner identifies the attachment a VF PCI address is allocated to
+type PCIOwner struct {
+	ContainerID string `json:"containerID"`
+	IfName      string `json:"ifName"`
+	NetnsPath   string `json:"netns"`
+	// NetnsInode tells a netns apart from a later one mounted at the same path, e.g. after a reboot
+	NetnsInode uint64 `json:"netnsInode"`
+}
@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
+
+// Mocked devlink interface, this is required for unit tests
+
+// DevlinkManager is an interface to mock the devlink part of the netlink library
+type DevlinkManager interface {
+	DevLinkGetDeviceByName(bus, device string) (*netlink.DevlinkDevice, error)
+	DevLinkGetAllPortList() ([]*netlink.DevlinkPort, error)
+	DevlinkPortFnSet(bus, device string, portIndex uint32, attrs netlink.DevlinkPortFnSetAttrs) error
+	DevlinkPortFnCapsGet(bus, device string, portIndex uint32) (uint32, error)
+	DevlinkPortFnCapsSet(bus, device string, portIndex uint32, caps, selector uint32) error
+	DevlinkRateList(bus, device string) ([]*sriovtypes.DevlinkRate, error)
+	DevlinkRateNodeNew(bus, device, name string) error
+	DevlinkRateNodeDel(bus, device, name string) error
+	DevlinkRateLeafSet(bus, device string, portIndex uint32, txShare, txMax uint64, parent string) error
+}
+
+const (
+	// EswitchModeLegacy is the eswitch mode of a PF whose VFs are configured through the IFLA_VF_* attributes
+	EswitchModeLegacy = "legacy"
+	// EswitchModeSwitchdev is the eswitch mode of a PF whose VFs are represented by representor netdevs
+	EswitchModeSwitchdev = "switchdev"
+)
+
+const (
+	// PortFnCapRoce is the RoCE capability bit of a devlink port function
+	PortFnCapRoce uint32 = unix.DEVLINK_PORT_FN_CAP_ROCE
+	// PortFnCapMigratable is the migratable capability bit of a devlink port function
+	PortFnCapMigratable uint32 = unix.DEVLINK_PORT_FN_CAP_MIGRATABLE
+	// PortFnCapIpsecCrypto is the IPsec crypto offload capability bit of a devlink port function
+	PortFnCapIpsecCrypto uint32 = unix.DEVLINK_PORT_FN_CAP_IPSEC_CRYPTO
+
+	// PortFnCapsAll selects every capability bit configurable through PortFunction
+	PortFnCapsAll = PortFnCapRoce | PortFnCapMigratable | PortFnCapIpsecCrypto
+	// PortFnCapsDefault is the capabilities a port function has when its VF is created: RoCE on, the others off
+	PortFnCapsDefault = PortFnCapRoce
+)
+
+// devlinkRateTypeLeaf is DEVLINK_RATE_TYPE_LEAF, the DEVLINK_ATTR_RATE_TYPE of the rate object of a port,
+// which golang.org/x/sys/unix doesn't define
+const devlinkRateTypeLeaf = 0
+
+// MyDevlink DevlinkManager
+type MyDevlink struct {
+	DevlinkManager
+}
+
+// DevLinkGetDeviceByName returns the devlink device bus/device along with its eswitch attributes
+// Equivalent to: `devlink dev eswitch show $bus/$device`
+func (d *MyDevlink) DevLinkGetDeviceByName(bus, device string) (*netlink.DevlinkDevice, error) {
+	return netlink.DevLinkGetDeviceByName(bus, device)
+}
+
+// DevLinkGetAllPortList returns the devlink ports of all the devlink devices
+// Equivalent to: `devlink port show`
+func (d *MyDevlink) DevLinkGetAllPortList() ([]*netlink.DevlinkPort, error) {
+	return netlink.DevLinkGetAllPortList()
+}
+
+// DevlinkPortFnSet sets the port function attributes of the devlink port portIndex of bus/device selected in attrs
+// Equivalent to: `devlink port function set $bus/$device/$portIndex hw_addr $hwAddr`
+func (d *MyDevlink) DevlinkPortFnSet(bus, device string, portIndex uint32, attrs netlink.DevlinkPortFnSetAttrs) error {
+	return netlink.DevlinkPortFnSet(bus, device, portIndex, attrs)
+}
+
+// newDevlinkRequest returns a devlink request cmd, for the devlink device bus/device unless bus is empty
+func newDevlinkRequest(cmd uint8, flags int, bus, device string) (*nl.NetlinkRequest, error) {
+	family, err := netlink.GenlFamilyGet(nl.GENL_DEVLINK_NAME)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get devlink generic netlink family: %w", err)
+	}
+
+	req := nl.NewNetlinkRequest(int(family.ID), flags)
+	req.AddData(&nl.Genlmsg{Command: cmd, Version: nl.GENL_DEVLINK_VERSION})
+	if bus != "" {
+		req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_BUS_NAME, nl.ZeroTerminated(bus)))
+		req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_DEV_NAME, nl.ZeroTerminated(device)))
+	}
+	return req, nil
+}
+
+// newDevlinkPortRequest returns a devlink request cmd for the devlink port portIndex of bus/device
+func newDevlinkPortRequest(cmd uint8, bus, device string, portIndex uint32) (*nl.NetlinkRequest, error) {
+	req, err := newDevlinkRequest(cmd, unix.NLM_F_ACK, bus, device)
+	if err != nil {
+		return nil, err
+	}
+	req.AddData(nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_INDEX, nl.Uint32Attr(portIndex)))
+	return req, nil
+}
+
+// DevlinkPortFnCapsGet returns the capability bits, PortFnCap*, of the port function of the devlink port portIndex
+// of bus/device. The returned error wraps EOPNOTSUPP when the kernel or driver reports no capabilities.
+// Equivalent to: `devlink port function show $bus/$device/$portIndex`
+func (d *MyDevlink) DevlinkPortFnCapsGet(bus, device string, portIndex uint32) (uint32, error) {
+	req, err := newDevlinkPortRequest(nl.DEVLINK_CMD_PORT_GET, bus, device, portIndex)
+	if err != nil {
+		return 0, err
+	}
+
+	msgs, err := req.Execute(unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return 0, err
+	}
+	if len(msgs) == 0 {
+		return 0, fmt.Errorf("no port information returned for %s/%s/%d", bus, device, portIndex)
+	}
+
+	attrs, err := nl.ParseRouteAttr(msgs[0][nl.SizeofGenlmsg:])
+	if err != nil {
+		return 0, err
+	}
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != nl.DEVLINK_ATTR_PORT_FUNCTION {
+			continue
+		}
+		fnAttrs, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return 0, err
+		}
+		for _, fnAttr := range fnAttrs {
+			// struct nla_bitfield32 { __u32 value; __u32 selector; }
+			if fnAttr.Attr.Type&nl.NLA_TYPE_MASK == unix.DEVLINK_PORT_FN_ATTR_CAPS && len(fnAttr.Value) >= 4 {
+				return nl.NativeEndian().Uint32(fnAttr.Value[:4]), nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("port function of %s/%s/%d reports no capabilities: %w", bus, device, portIndex, syscall.EOPNOTSUPP)
+}
+
+// DevlinkPortFnCapsSet sets the capability bits of the port function of the devlink port portIndex of bus/device
+// that are set in selector to their value in caps
+// Equivalent to: `devlink port function set $bus/$device/$portIndex roce enable|disable ...`
+func (d *MyDevlink) DevlinkPortFnCapsSet(bus, device string, portIndex uint32, caps, selector uint32) error {
+	req, err := newDevlinkPortRequest(nl.DEVLINK_CMD_PORT_SET, bus, device, portIndex)
+	if err != nil {
+		return err
+	}
+
+	bitfield := make([]byte, 8)
+	nl.NativeEndian().PutUint32(bitfield[:4], caps)
+	nl.NativeEndian().PutUint32(bitfield[4:], selector)
+	fnAttr := nl.NewRtAttr(nl.DEVLINK_ATTR_PORT_FUNCTION|unix.NLA_F_NESTED, nil)
+	fnAttr.AddRtAttr(unix.DEVLINK_PORT_FN_ATTR_CAPS, bitfield)
+	req.AddData(fnAttr)
+
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// parseDevlinkRate parses the attributes of a rate object message
+func parseDevlinkRate(msg []byte) (*sriovtypes.DevlinkRate, error) {
+	attrs, err := nl.ParseRouteAttr(msg[nl.SizeofGenlmsg:])
+	if err != nil {
+		return nil, err
+	}
+
+	rate := &sriovtypes.DevlinkRate{}
+	for _, attr := range attrs {
+		switch attr.Attr.Type & nl.NLA_TYPE_MASK {
+		case nl.DEVLINK_ATTR_BUS_NAME:
+			rate.BusName = string(attr.Value[:len(attr.Value)-1])
+		case nl.DEVLINK_ATTR_DEV_NAME:
+			rate.DeviceName = string(attr.Value[:len(attr.Value)-1])
+		case nl.DEVLINK_ATTR_PORT_INDEX:
+			rate.PortIndex = nl.NativeEndian().Uint32(attr.Value)
+		case unix.DEVLINK_ATTR_RATE_TYPE:
+			rate.Leaf = nl.NativeEndian().Uint16(attr.Value) == devlinkRateTypeLeaf
+		case unix.DEVLINK_ATTR_RATE_TX_SHARE:
+			rate.TxShare = nl.NativeEndian().Uint64(attr.Value)
+		case unix.DEVLINK_ATTR_RATE_TX_MAX:
+			rate.TxMax = nl.NativeEndian().Uint64(attr.Value)
+		case unix.DEVLINK_ATTR_RATE_NODE_NAME:
+			rate.NodeName = string(attr.Value[:len(attr.Value)-1])
+		case unix.DEVLINK_ATTR_RATE_PARENT_NODE_NAME:
+			rate.Parent = string(attr.Value[:len(attr.Value)-1])
+		}
+	}
+	return rate, nil
+}
+
+// DevlinkRateList returns the rate objects, leaves and nodes, of the devlink device bus/device
+// Equivalent to: `devlink port function rate show $bus/$device`
+func (d *MyDevlink) DevlinkRateList(bus, device string) ([]*sriovtypes.DevlinkRate, error) {
+	req, err := newDevlinkRequest(unix.DEVLINK_CMD_RATE_GET, unix.NLM_F_DUMP, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := req.Execute(unix.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []*sriovtypes.DevlinkRate
+	for _, msg := range msgs {
+		rate, err := parseDevlinkRate(msg)
+		if err != nil {
+			return nil, err
+		}
+		// Older kernels dump the rate objects of every devlink device
+		if rate.BusName == bus && rate.DeviceName == device {
+			rates = append(rates, rate)
+		}
+	}
+	return rates, nil
+}
+
+// DevlinkRateNodeNew creates the rate node name of the devlink device bus/device, without rate limits
+// Equivalent to: `devlink port function rate add $bus/$device/$name`
+func (d *MyDevlink) DevlinkRateNodeNew(bus, device, name string) error {
+	req, err := newDevlinkRequest(unix.DEVLINK_CMD_RATE_NEW, unix.NLM_F_ACK, bus, device)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(unix.DEVLINK_ATTR_RATE_NODE_NAME, nl.ZeroTerminated(name)))
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// DevlinkRateNodeDel deletes the rate node name of the devlink device bus/device
+// Equivalent to: `devlink port function rate del $bus/$device/$name`
+func (d *MyDevlink) DevlinkRateNodeDel(bus, device, name string) error {
+	req, err := newDevlinkRequest(unix.DEVLINK_CMD_RATE_DEL, unix.NLM_F_ACK, bus, device)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(unix.DEVLINK_ATTR_RATE_NODE_NAME, nl.ZeroTerminated(name)))
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
+
+// DevlinkRateLeafSet sets the rate limits, in bytes per second, and the parent node of the rate leaf of the devlink
+// port portIndex of bus/device. An empty parent detaches the leaf from its parent node.
+// Equivalent to: `devlink port function rate set $bus/$device/$portIndex tx_share $txShare tx_max $txMax parent $parent`
+func (d *MyDevlink) DevlinkRateLeafSet(bus, device string, portIndex uint32, txShare, txMax uint64, parent string) error {
+	req, err := newDevlinkPortRequest(unix.DEVLINK_CMD_RATE_SET, bus, device, portIndex)
+	if err != nil {
+		return err
+	}
+	req.AddData(nl.NewRtAttr(unix.DEVLINK_ATTR_RATE_TX_SHARE, nl.Uint64Attr(txShare)))
+	req.AddData(nl.NewRtAttr(unix.DEVLINK_ATTR_RATE_TX_MAX, nl.Uint64Attr(txMax)))
+	req.AddData(nl.NewRtAttr(unix.DEVLINK_ATTR_RATE_PARENT_NODE_NAME, nl.ZeroTerminated(parent)))
+	_, err = req.Execute(unix.NETLINK_GENERIC, 0)
+	return err
+}
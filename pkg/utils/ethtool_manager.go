@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/safchain/ethtool"
+	"golang.org/x/sys/unix"
+)
+
+// Mocked ethtool interface, this is required for unit tests
+
+// EthtoolManager is an interface to mock the ethtool library
+// Its methods act on the netdev ifName of the netns the calling thread is in.
+type EthtoolManager interface {
+	DriverName(ifName string) (string, error)
+	Features(ifName string) (map[string]bool, error)
+	ChangeFeatures(ifName string, features map[string]bool) error
+	GetRing(ifName string) (rx, tx uint32, err error)
+	SetRing(ifName string, rx, tx uint32) error
+	GetChannels(ifName string) (ethtool.Channels, error)
+	SetChannels(ifName string, channels ethtool.Channels) error
+}
+
+// ethtoolRingParam is struct ethtool_ringparam
+type ethtoolRingParam struct {
+	cmd               uint32
+	rxMaxPending      uint32
+	rxMiniMaxPending  uint32
+	rxJumboMaxPending uint32
+	txMaxPending      uint32
+	rxPending         uint32
+	rxMiniPending     uint32
+	rxJumboPending    uint32
+	txPending         uint32
+}
+
+const (
+	ethtoolGRingParam = 0x00000010 // ETHTOOL_GRINGPARAM
+	ethtoolSRingParam = 0x00000011 // ETHTOOL_SRINGPARAM
+)
+
+// MyEthtool EthtoolManager
+type MyEthtool struct {
+	EthtoolManager
+}
+
+// withEthtool runs f with an ethtool handle of the current netns
+func withEthtool(f func(e *ethtool.Ethtool) error) error {
+	e, err := ethtool.NewEthtool()
+	if err != nil {
+		return fmt.Errorf("failed to open ethtool socket: %v", err)
+	}
+	defer e.Close()
+	return f(e)
+}
+
+// DriverName using EthtoolManager
+func (m *MyEthtool) DriverName(ifName string) (driver string, err error) {
+	err = withEthtool(func(e *ethtool.Ethtool) error {
+		driver, err = e.DriverName(ifName)
+		return err
+	})
+	return driver, err
+}
+
+// Features returns the state of the features of ifName, by kernel feature name
+func (m *MyEthtool) Features(ifName string) (features map[string]bool, err error) {
+	err = withEthtool(func(e *ethtool.Ethtool) error {
+		features, err = e.Features(ifName)
+		return err
+	})
+	return features, err
+}
+
+// ChangeFeatures turns the features of ifName on or off, by kernel feature name
+// Features unknown to the driver are reported as EOPNOTSUPP, like features the driver doesn't let change.
+// The other features are already changed when a fixed one is reported.
+func (m *MyEthtool) ChangeFeatures(ifName string, features map[string]bool) error {
+	return withEthtool(func(e *ethtool.Ethtool) error {
+		names, err := e.FeatureNames(ifName)
+		if err != nil {
+			return err
+		}
+		for name := range features {
+			if _, ok := names[name]; !ok {
+				return fmt.Errorf("unknown feature %s: %w", name, syscall.EOPNOTSUPP)
+			}
+		}
+
+		if err = e.Change(ifName, features); err != nil {
+			return err
+		}
+
+		// The kernel silently ignores the features that are fixed for the device
+		current, err := e.Features(ifName)
+		if err != nil {
+			return err
+		}
+		for name, on := range features {
+			if current[name] != on {
+				return fmt.Errorf("feature %s is fixed: %w", name, syscall.EOPNOTSUPP)
+			}
+		}
+		return nil
+	})
+}
+
+// ringParamIoctl runs the ring parameters ethtool request ring on ifName
+func ringParamIoctl(ifName string, ring *ethtoolRingParam) error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, unix.IPPROTO_IP)
+	if err != nil {
+		return fmt.Errorf("failed to open ethtool socket: %v", err)
+	}
+	defer unix.Close(fd)
+
+	var ifr struct {
+		name [unix.IFNAMSIZ]byte
+		data uintptr
+	}
+	copy(ifr.name[:], ifName)
+	ifr.data = uintptr(unsafe.Pointer(ring))
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	runtime.KeepAlive(ring)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// GetRing returns the rx and tx ring sizes of ifName
+// Equivalent to: `ethtool -g $ifName`
+func (m *MyEthtool) GetRing(ifName string) (rx, tx uint32, err error) {
+	ring := ethtoolRingParam{cmd: ethtoolGRingParam}
+	if err = ringParamIoctl(ifName, &ring); err != nil {
+		return 0, 0, err
+	}
+	return ring.rxPending, ring.txPending, nil
+}
+
+// SetRing sets the rx and tx ring sizes of ifName, leaving its mini and jumbo rings unchanged
+// Equivalent to: `ethtool -G $ifName rx $rx tx $tx`
+func (m *MyEthtool) SetRing(ifName string, rx, tx uint32) error {
+	ring := ethtoolRingParam{cmd: ethtoolGRingParam}
+	if err := ringParamIoctl(ifName, &ring); err != nil {
+		return err
+	}
+	ring.cmd = ethtoolSRingParam
+	ring.rxPending, ring.txPending = rx, tx
+	return ringParamIoctl(ifName, &ring)
+}
+
+// GetChannels returns the channel counts of ifName
+// Equivalent to: `ethtool -l $ifName`
+func (m *MyEthtool) GetChannels(ifName string) (channels ethtool.Channels, err error) {
+	err = withEthtool(func(e *ethtool.Ethtool) error {
+		channels, err = e.GetChannels(ifName)
+		return err
+	})
+	return channels, err
+}
+
+// SetChannels sets the channel counts of ifName
+// Equivalent to: `ethtool -L $ifName rx $rx tx $tx other $other combined $combined`
+func (m *MyEthtool) SetChannels(ifName string, channels ethtool.Channels) error {
+	return withEthtool(func(e *ethtool.Ethtool) error {
+		_, err := e.SetChannels(ifName, channels)
+		return err
+	})
+}
@@ -38,6 +38,99 @@ func (_m *NetlinkManager) LinkByName(_a0 string) (netlink.Link, error) {
 	return r0, r1
 }
 
+// LinkGetMaxMTU provides a mock function with given fields: _a0
+func (_m *NetlinkManager) LinkGetMaxMTU(_a0 netlink.Link) (int, error) {
+	ret := _m.Called(_a0)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(netlink.Link) int); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(netlink.Link) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkGetVfVlanProto provides a mock function with given fields: _a0, _a1
+func (_m *NetlinkManager) LinkGetVfVlanProto(_a0 netlink.Link, _a1 int) (int, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) int); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(netlink.Link, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkList provides a mock function with given fields:
+func (_m *NetlinkManager) LinkList() ([]netlink.Link, error) {
+	ret := _m.Called()
+
+	var r0 []netlink.Link
+	if rf, ok := ret.Get(0).(func() []netlink.Link); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netlink.Link)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkSetAllmulticastOff provides a mock function with given fields: _a0
+func (_m *NetlinkManager) LinkSetAllmulticastOff(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetAllmulticastOn provides a mock function with given fields: _a0
+func (_m *NetlinkManager) LinkSetAllmulticastOn(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetDown provides a mock function with given fields: _a0
 func (_m *NetlinkManager) LinkSetDown(_a0 netlink.Link) error {
 	ret := _m.Called(_a0)
@@ -66,6 +159,20 @@ func (_m *NetlinkManager) LinkSetHardwareAddr(_a0 netlink.Link, _a1 net.Hardware
 	return r0
 }
 
+// LinkSetMTU provides a mock function with given fields: _a0, _a1
+func (_m *NetlinkManager) LinkSetMTU(_a0 netlink.Link, _a1 int) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetName provides a mock function with given fields: _a0, _a1
 func (_m *NetlinkManager) LinkSetName(_a0 netlink.Link, _a1 string) error {
 	ret := _m.Called(_a0, _a1)
@@ -94,6 +201,48 @@ func (_m *NetlinkManager) LinkSetNsFd(_a0 netlink.Link, _a1 int) error {
 	return r0
 }
 
+// LinkSetPromiscOff provides a mock function with given fields: _a0
+func (_m *NetlinkManager) LinkSetPromiscOff(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetPromiscOn provides a mock function with given fields: _a0
+func (_m *NetlinkManager) LinkSetPromiscOn(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetTxQLen provides a mock function with given fields: _a0, _a1
+func (_m *NetlinkManager) LinkSetTxQLen(_a0 netlink.Link, _a1 int) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetUp provides a mock function with given fields: _a0
 func (_m *NetlinkManager) LinkSetUp(_a0 netlink.Link) error {
 	ret := _m.Called(_a0)
@@ -206,6 +355,20 @@ func (_m *NetlinkManager) LinkSetVfVlanQos(_a0 netlink.Link, _a1 int, _a2 int, _
 	return r0
 }
 
+// LinkSetVfVlanQosProto provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *NetlinkManager) LinkSetVfVlanQosProto(_a0 netlink.Link, _a1 int, _a2 int, _a3 int, _a4 int) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, int, int, int) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewNetlinkManager interface {
 	mock.TestingT
 	Cleanup(func())
@@ -2,20 +2,38 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	types "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
+)
 
 // PCIAllocation is an autogenerated mock type for the PCIAllocation type
 type PCIAllocation struct {
 	mock.Mock
 }
 
-// CleanAllocatedPCI provides a mock function with given fields: _a0
-func (_m *PCIAllocation) CleanAllocatedPCI(_a0 string) error {
-	ret := _m.Called(_a0)
+// ClaimPCI provides a mock function with given fields: _a0, _a1
+func (_m *PCIAllocation) ClaimPCI(_a0 string, _a1 *types.PCIOwner) error {
+	ret := _m.Called(_a0, _a1)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(string, *types.PCIOwner) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteAllocatedPCI provides a mock function with given fields: _a0, _a1
+func (_m *PCIAllocation) DeleteAllocatedPCI(_a0 string, _a1 *types.PCIOwner) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *types.PCIOwner) error); ok {
+		r0 = rf(_a0, _a1)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -24,7 +42,7 @@ func (_m *PCIAllocation) CleanAllocatedPCI(_a0 string) error {
 }
 
 // IsAllocated provides a mock function with given fields: _a0
-func (_m *PCIAllocation) IsAllocated(_a0 string) (bool, string, error) {
+func (_m *PCIAllocation) IsAllocated(_a0 string) (bool, error) {
 	ret := _m.Called(_a0)
 
 	var r0 bool
@@ -34,35 +52,14 @@ func (_m *PCIAllocation) IsAllocated(_a0 string) (bool, string, error) {
 		r0 = ret.Get(0).(bool)
 	}
 
-	var r1 string
-	if rf, ok := ret.Get(1).(func(string) string); ok {
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
 		r1 = rf(_a0)
 	} else {
-		r1 = ret.Get(1).(string)
-	}
-
-	var r2 error
-	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(_a0)
-	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2
-}
-
-// SaveAllocatedPCI provides a mock function with given fields: _a0, _a1
-func (_m *PCIAllocation) SaveAllocatedPCI(_a0 string, _a1 string) error {
-	ret := _m.Called(_a0, _a1)
-
-	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(_a0, _a1)
-	} else {
-		r0 = ret.Error(0)
-	}
-
-	return r0
+	return r0, r1
 }
 
 type mockConstructorTestingTNewPCIAllocation interface {
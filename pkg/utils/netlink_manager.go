@@ -1,9 +1,14 @@
 package utils
 
 import (
+	"encoding/binary"
+	"fmt"
 	"net"
+	"syscall"
 
 	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 )
 
 // Mocked netlink interface, this is required for unit tests
@@ -11,8 +16,11 @@ import (
 // NetlinkManager is an interface to mock nelink library
 type NetlinkManager interface {
 	LinkByName(string) (netlink.Link, error)
+	LinkList() ([]netlink.Link, error)
 	LinkSetVfVlan(netlink.Link, int, int) error
 	LinkSetVfVlanQos(netlink.Link, int, int, int) error
+	LinkSetVfVlanQosProto(netlink.Link, int, int, int, int) error
+	LinkGetVfVlanProto(netlink.Link, int) (int, error)
 	LinkSetVfHardwareAddr(netlink.Link, int, net.HardwareAddr) error
 	LinkSetHardwareAddr(netlink.Link, net.HardwareAddr) error
 	LinkSetUp(netlink.Link) error
@@ -23,6 +31,13 @@ type NetlinkManager interface {
 	LinkSetVfSpoofchk(netlink.Link, int, bool) error
 	LinkSetVfTrust(netlink.Link, int, bool) error
 	LinkSetVfState(netlink.Link, int, uint32) error
+	LinkSetMTU(netlink.Link, int) error
+	LinkGetMaxMTU(netlink.Link) (int, error)
+	LinkSetTxQLen(netlink.Link, int) error
+	LinkSetPromiscOn(netlink.Link) error
+	LinkSetPromiscOff(netlink.Link) error
+	LinkSetAllmulticastOn(netlink.Link) error
+	LinkSetAllmulticastOff(netlink.Link) error
 }
 
 // MyNetlink NetlinkManager
@@ -35,6 +50,11 @@ func (n *MyNetlink) LinkByName(name string) (netlink.Link, error) {
 	return netlink.LinkByName(name)
 }
 
+// LinkList implements NetlinkManager
+func (n *MyNetlink) LinkList() ([]netlink.Link, error) {
+	return netlink.LinkList()
+}
+
 // LinkSetVfVlan using NetlinkManager
 func (n *MyNetlink) LinkSetVfVlan(link netlink.Link, vf, vlan int) error {
 	return netlink.LinkSetVfVlan(link, vf, vlan)
@@ -45,6 +65,100 @@ func (n *MyNetlink) LinkSetVfVlanQos(link netlink.Link, vf, vlan, qos int) error
 	return netlink.LinkSetVfVlanQos(link, vf, vlan, qos)
 }
 
+// sizeofVfVlanInfo is the size of struct ifla_vf_vlan_info, including its trailing padding
+const sizeofVfVlanInfo = 16
+
+// LinkSetVfVlanQosProto sets the VLAN ID, QoS and VLAN protocol of a VF through the IFLA_VF_VLAN_LIST
+// attribute, which unlike IFLA_VF_VLAN carries the protocol
+// Equivalent to: `ip link set $link vf $vf vlan $vlan qos $qos proto $proto`
+func (n *MyNetlink) LinkSetVfVlanQosProto(link netlink.Link, vf, vlan, qos, proto int) error {
+	req := nl.NewNetlinkRequest(unix.RTM_SETLINK, unix.NLM_F_ACK)
+
+	msg := nl.NewIfInfomsg(unix.AF_UNSPEC)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+
+	vlanInfo := make([]byte, sizeofVfVlanInfo)
+	nl.NativeEndian().PutUint32(vlanInfo[0:4], uint32(vf))
+	nl.NativeEndian().PutUint32(vlanInfo[4:8], uint32(vlan))
+	nl.NativeEndian().PutUint32(vlanInfo[8:12], uint32(qos))
+	binary.BigEndian.PutUint16(vlanInfo[12:14], uint16(proto))
+
+	data := nl.NewRtAttr(unix.IFLA_VFINFO_LIST, nil)
+	info := data.AddRtAttr(nl.IFLA_VF_INFO, nil)
+	vlanList := info.AddRtAttr(unix.IFLA_VF_VLAN_LIST, nil)
+	vlanList.AddRtAttr(unix.IFLA_VF_VLAN_INFO, vlanInfo)
+	req.AddData(data)
+
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+// getLinkRouteAttrs returns the raw attributes of link, for those not parsed by the netlink library
+// extMask is the IFLA_EXT_MASK of the request, selecting the optional attributes to report.
+func getLinkRouteAttrs(link netlink.Link, extMask uint32) ([]syscall.NetlinkRouteAttr, error) {
+	req := nl.NewNetlinkRequest(unix.RTM_GETLINK, unix.NLM_F_ACK)
+
+	msg := nl.NewIfInfomsg(unix.AF_UNSPEC)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+	if extMask != 0 {
+		req.AddData(nl.NewRtAttr(unix.IFLA_EXT_MASK, nl.Uint32Attr(extMask)))
+	}
+
+	msgs, err := req.Execute(unix.NETLINK_ROUTE, unix.RTM_NEWLINK)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("no link information returned for %s", link.Attrs().Name)
+	}
+
+	return nl.ParseRouteAttr(msgs[0][msg.Len():])
+}
+
+// LinkGetVfVlanProto returns the VLAN protocol of a VF as reported in the IFLA_VF_VLAN_LIST attribute
+// Drivers that don't report the attribute only support 802.1Q.
+func (n *MyNetlink) LinkGetVfVlanProto(link netlink.Link, vf int) (int, error) {
+	attrs, err := getLinkRouteAttrs(link, nl.RTEXT_FILTER_VF)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, attr := range attrs {
+		if attr.Attr.Type != unix.IFLA_VFINFO_LIST {
+			continue
+		}
+		vfInfos, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return 0, err
+		}
+		for _, vfInfo := range vfInfos {
+			vfAttrs, err := nl.ParseRouteAttr(vfInfo.Value)
+			if err != nil {
+				return 0, err
+			}
+			for _, vfAttr := range vfAttrs {
+				if vfAttr.Attr.Type != unix.IFLA_VF_VLAN_LIST {
+					continue
+				}
+				vlanInfos, err := nl.ParseRouteAttr(vfAttr.Value)
+				if err != nil {
+					return 0, err
+				}
+				for _, vlanInfo := range vlanInfos {
+					if len(vlanInfo.Value) < 14 || nl.NativeEndian().Uint32(vlanInfo.Value[0:4]) != uint32(vf) {
+						continue
+					}
+					return int(binary.BigEndian.Uint16(vlanInfo.Value[12:14])), nil
+				}
+			}
+		}
+	}
+
+	return int(netlink.VLAN_PROTOCOL_8021Q), nil
+}
+
 // LinkSetVfHardwareAddr using NetlinkManager
 func (n *MyNetlink) LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error {
 	return netlink.LinkSetVfHardwareAddr(link, vf, hwaddr)
@@ -94,3 +208,49 @@ func (n *MyNetlink) LinkSetVfTrust(link netlink.Link, vf int, state bool) error
 func (n *MyNetlink) LinkSetVfState(link netlink.Link, vf int, state uint32) error {
 	return netlink.LinkSetVfState(link, vf, state)
 }
+
+// LinkSetMTU using NetlinkManager
+func (n *MyNetlink) LinkSetMTU(link netlink.Link, mtu int) error {
+	return netlink.LinkSetMTU(link, mtu)
+}
+
+// LinkGetMaxMTU returns the maximum MTU of link as reported in the IFLA_MAX_MTU attribute,
+// or 0 if the kernel doesn't report it
+func (n *MyNetlink) LinkGetMaxMTU(link netlink.Link) (int, error) {
+	attrs, err := getLinkRouteAttrs(link, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, attr := range attrs {
+		if attr.Attr.Type == unix.IFLA_MAX_MTU && len(attr.Value) >= 4 {
+			return int(nl.NativeEndian().Uint32(attr.Value[0:4])), nil
+		}
+	}
+	return 0, nil
+}
+
+// LinkSetTxQLen using NetlinkManager
+func (n *MyNetlink) LinkSetTxQLen(link netlink.Link, qlen int) error {
+	return netlink.LinkSetTxQLen(link, qlen)
+}
+
+// LinkSetPromiscOn using NetlinkManager
+func (n *MyNetlink) LinkSetPromiscOn(link netlink.Link) error {
+	return netlink.SetPromiscOn(link)
+}
+
+// LinkSetPromiscOff using NetlinkManager
+func (n *MyNetlink) LinkSetPromiscOff(link netlink.Link) error {
+	return netlink.SetPromiscOff(link)
+}
+
+// LinkSetAllmulticastOn using NetlinkManager
+func (n *MyNetlink) LinkSetAllmulticastOn(link netlink.Link) error {
+	return netlink.LinkSetAllmulticastOn(link)
+}
+
+// LinkSetAllmulticastOff using NetlinkManager
+func (n *MyNetlink) LinkSetAllmulticastOff(link netlink.Link) error {
+	return netlink.LinkSetAllmulticastOff(link)
+}
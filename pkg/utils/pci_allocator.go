@@ -1,16 +1,58 @@
 package utils
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/containernetworking/plugins/pkg/ns"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/containernetworking/plugins/pkg/ns"
+	srioverrors "github.com/k8snetworkplumbingwg/sriov-cni/pkg/errors"
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 )
 
+// ErrNotOwner is returned when deleting a PCI allocation that belongs to another attachment
+var ErrNotOwner = errors.New("pci address is allocated to another attachment")
+
+// PCIAllocation is an interface to track the PCI addresses in use by pods
 type PCIAllocation interface {
-	SaveAllocatedPCI(string, string) error
-	DeleteAllocatedPCI(string) error
-	IsAllocated(string) error
+	ClaimPCI(string, *sriovtypes.PCIOwner) error
+	DeleteAllocatedPCI(string, *sriovtypes.PCIOwner) error
+	IsAllocated(string) (bool, error)
+}
+
+// NewPCIOwner returns the owner record of an attachment
+// The netns inode is left empty if the netns can't be found, as is the case on DEL after a reboot.
+func NewPCIOwner(containerID, ifName, netnsPath string) *sriovtypes.PCIOwner {
+	owner := &sriovtypes.PCIOwner{ContainerID: containerID, IfName: ifName, NetnsPath: netnsPath}
+	if inode, err := getInode(netnsPath); err == nil {
+		owner.NetnsInode = inode
+	}
+	return owner
+}
+
+// ownsAllocation returns whether the owner recorded in an allocation is the attachment owner
+// Allocations written by older versions only record the netns path and are owned by any attachment.
+func ownsAllocation(owner, recorded *sriovtypes.PCIOwner) bool {
+	if recorded.ContainerID == "" {
+		return true
+	}
+	return owner.ContainerID == recorded.ContainerID && owner.IfName == recorded.IfName
+}
+
+func getInode(path string) (uint64, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("failed to get inode of %s", path)
+	}
+	return stat.Ino, nil
 }
 
 type PCIAllocator struct {
@@ -23,25 +65,84 @@ func NewPCIAllocator(dataDir string) *PCIAllocator {
 	return &PCIAllocator{dataDir: filepath.Join(dataDir, "pci")}
 }
 
-// SaveAllocatedPCI creates a file with the pci address as a name and the network namespace as the content
-// return error if the file was not created
-func (p *PCIAllocator) SaveAllocatedPCI(pciAddress, ns string) error {
-	if err := os.MkdirAll(p.dataDir, 0600); err != nil {
-		return fmt.Errorf("failed to create the sriov data directory(%q): %v", p.dataDir, err)
+// ClaimPCI atomically marks the pci address as in use by owner
+// It returns an error if the pci address is already allocated to a network namespace that still exists.
+// A claim left by a network namespace that is gone is considered stale and taken over.
+func (p *PCIAllocator) ClaimPCI(pciAddress string, owner *sriovtypes.PCIOwner) error {
+	unlock, err := p.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	isAllocated, err := p.isAllocated(pciAddress)
+	if err != nil {
+		return err
+	}
+	if isAllocated {
+		return srioverrors.AlreadyAllocated(pciAddress)
+	}
+
+	return p.saveAllocatedPCI(pciAddress, owner)
+}
+
+// saveAllocatedPCI creates a file with the pci address as a name and the owner record as the content,
+// the caller holds the lock of the allocator
+func (p *PCIAllocator) saveAllocatedPCI(pciAddress string, owner *sriovtypes.PCIOwner) error {
+	data, err := json.Marshal(owner)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the owner of pci address %s: %v", pciAddress, err)
 	}
 
+	// The file is written under a temporary name, synced and renamed into place so that neither a reader
+	// nor a crash ever leaves a partially written allocation
 	path := filepath.Join(p.dataDir, pciAddress)
-	err := os.WriteFile(path, []byte(ns), 0600)
+	tmpPath := path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
+		return fmt.Errorf("failed to write used PCI address lock file in the path(%q): %v", tmpPath, err)
+	}
+	defer os.Remove(tmpPath)
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write used PCI address lock file in the path(%q): %v", tmpPath, err)
+	}
+
+	if err = os.Rename(tmpPath, path); err != nil {
 		return fmt.Errorf("failed to write used PCI address lock file in the path(%q): %v", path, err)
 	}
 
-	return err
+	return syncDir(p.dataDir)
+}
+
+// DeleteAllocatedPCI Remove the allocated PCI file if it is owned by owner
+// return error if the file doesn't exist, or an error wrapping ErrNotOwner if it belongs to another attachment
+func (p *PCIAllocator) DeleteAllocatedPCI(pciAddress string, owner *sriovtypes.PCIOwner) error {
+	unlock, err := p.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	recorded, err := p.readAllocatedPCI(pciAddress)
+	if err != nil {
+		return err
+	}
+	if !ownsAllocation(owner, recorded) {
+		return fmt.Errorf("not deleting allocation of %s held by container %s interface %s: %w",
+			pciAddress, recorded.ContainerID, recorded.IfName, ErrNotOwner)
+	}
+
+	return p.deleteAllocatedPCI(pciAddress)
 }
 
-// DeleteAllocatedPCI Remove the allocated PCI file
-// return error if the file doesn't exist
-func (p *PCIAllocator) DeleteAllocatedPCI(pciAddress string) error {
+func (p *PCIAllocator) deleteAllocatedPCI(pciAddress string) error {
 	path := filepath.Join(p.dataDir, pciAddress)
 	if err := os.Remove(path); err != nil {
 		return fmt.Errorf("error removing PCI address lock file %s: %v", path, err)
@@ -49,10 +150,73 @@ func (p *PCIAllocator) DeleteAllocatedPCI(pciAddress string) error {
 	return nil
 }
 
+// IsOwner returns whether the pci address is allocated to owner
+func (p *PCIAllocator) IsOwner(pciAddress string, owner *sriovtypes.PCIOwner) (bool, error) {
+	unlock, err := p.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if _, err = os.Stat(filepath.Join(p.dataDir, pciAddress)); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	recorded, err := p.readAllocatedPCI(pciAddress)
+	if err != nil {
+		return false, err
+	}
+	return ownsAllocation(owner, recorded), nil
+}
+
+// FindAllocatedPCI returns the pci address allocated to owner, or an empty string if there is none
+// Allocations written by older versions don't record their owner and are never returned, nor are the
+// allocations that can't be read.
+func (p *PCIAllocator) FindAllocatedPCI(owner *sriovtypes.PCIOwner) (string, error) {
+	unlock, err := p.lock()
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(p.dataDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read PCI allocation directory %s: %v", p.dataDir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+
+		// An allocation that can't be parsed can't be attributed to its owner, it must not prevent the
+		// allocation of owner from being found
+		recorded, err := p.readAllocatedPCI(entry.Name())
+		if err != nil {
+			continue
+		}
+		if recorded.ContainerID != "" && ownsAllocation(owner, recorded) {
+			return entry.Name(), nil
+		}
+	}
+
+	return "", nil
+}
+
 // IsAllocated checks if the PCI address file exist
 // if it exists we also check the network namespace still exist if not we delete the allocation
 // The function will return an error if the pci is still allocated to a running pod
 func (p *PCIAllocator) IsAllocated(pciAddress string) (bool, error) {
+	unlock, err := p.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	return p.isAllocated(pciAddress)
+}
+
+func (p *PCIAllocator) isAllocated(pciAddress string) (bool, error) {
 	path := filepath.Join(p.dataDir, pciAddress)
 	_, err := os.Stat(path)
 	if err != nil {
@@ -63,17 +227,16 @@ func (p *PCIAllocator) IsAllocated(pciAddress string) (bool, error) {
 		return false, fmt.Errorf("failed to check for pci address file for %s: %v", path, err)
 	}
 
-	dat, err := os.ReadFile(path)
+	recorded, err := p.readAllocatedPCI(pciAddress)
 	if err != nil {
-		return false, fmt.Errorf("failed to read for pci address file for %s: %v", path, err)
+		return false, err
 	}
 
-	// To prevent a locking of a PCI address for every pciAddress file we also add the netns path where it's been used
+	// To prevent a locking of a PCI address for every pciAddress file we also add the netns where it's been used
 	// This way if for some reason the cmdDel command was not called but the pod namespace doesn't exist anymore
-	// we release the PCI address
-	networkNamespace, err := ns.GetNS(string(dat))
-	if err != nil {
-		err = p.DeleteAllocatedPCI(pciAddress)
+	// we release the PCI address. Netns paths are reused after a reboot, so the netns inode is compared as well.
+	if !netnsExists(recorded) {
+		err = p.deleteAllocatedPCI(pciAddress)
 		if err != nil {
 			return false, fmt.Errorf("error deleting the pci allocation for vf pci address %s: %v", pciAddress, err)
 		}
@@ -81,7 +244,66 @@ func (p *PCIAllocator) IsAllocated(pciAddress string) (bool, error) {
 		return false, nil
 	}
 
-	// Close the network namespace
-	networkNamespace.Close()
 	return true, nil
 }
+
+// readAllocatedPCI returns the owner recorded in the allocation file of the pci address
+func (p *PCIAllocator) readAllocatedPCI(pciAddress string) (*sriovtypes.PCIOwner, error) {
+	path := filepath.Join(p.dataDir, pciAddress)
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read for pci address file for %s: %w", path, err)
+	}
+
+	owner := &sriovtypes.PCIOwner{}
+	if !strings.HasPrefix(string(dat), "{") {
+		// Older versions only recorded the netns path
+		owner.NetnsPath = string(dat)
+		return owner, nil
+	}
+
+	if err := json.Unmarshal(dat, owner); err != nil {
+		return nil, fmt.Errorf("failed to parse pci address file for %s: %v", path, err)
+	}
+	return owner, nil
+}
+
+// netnsExists returns whether the netns recorded in an allocation still exists
+func netnsExists(owner *sriovtypes.PCIOwner) bool {
+	if owner.NetnsInode == 0 {
+		networkNamespace, err := ns.GetNS(owner.NetnsPath)
+		if err != nil {
+			return false
+		}
+		networkNamespace.Close()
+		return true
+	}
+
+	inode, err := getInode(owner.NetnsPath)
+	return err == nil && inode == owner.NetnsInode
+}
+
+// lock takes an exclusive lock on the allocation directory, serializing allocator operations across
+// concurrent plugin invocations. The lock is released by the kernel if the process dies while holding it,
+// so it can never be left stale.
+func (p *PCIAllocator) lock() (func(), error) {
+	if err := os.MkdirAll(p.dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the sriov data directory(%q): %v", p.dataDir, err)
+	}
+
+	lockPath := filepath.Join(p.dataDir, ".lock")
+	f, err := os.OpenFile(lockPath, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PCI allocation lock file %s: %v", lockPath, err)
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock PCI allocation lock file %s: %v", lockPath, err)
+	}
+
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -1,6 +1,9 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -32,7 +35,7 @@ var _ = Describe("PCIAllocator", func() {
 			Expect(err).NotTo(HaveOccurred())
 			allocator := NewPCIAllocator(ts.dirRoot)
 
-			err = allocator.SaveAllocatedPCI("0000:af:00.1", targetNetNS.Path())
+			err = allocator.saveAllocatedPCI("0000:af:00.1", NewPCIOwner("cid", "net1", targetNetNS.Path()))
 			Expect(err).ToNot(HaveOccurred())
 
 			isAllocated, err := allocator.IsAllocated("0000:af:00.1")
@@ -45,7 +48,7 @@ var _ = Describe("PCIAllocator", func() {
 			Expect(err).NotTo(HaveOccurred())
 
 			allocator := NewPCIAllocator(ts.dirRoot)
-			err = allocator.SaveAllocatedPCI("0000:af:00.1", targetNetNS.Path())
+			err = allocator.saveAllocatedPCI("0000:af:00.1", NewPCIOwner("cid", "net1", targetNetNS.Path()))
 			Expect(err).ToNot(HaveOccurred())
 			err = targetNetNS.Close()
 			Expect(err).ToNot(HaveOccurred())
@@ -57,4 +60,98 @@ var _ = Describe("PCIAllocator", func() {
 			Expect(isAllocated).To(BeFalse())
 		})
 	})
+
+	Context("ClaimPCI", func() {
+		It("Assuming is not allocated", func() {
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			allocator := NewPCIAllocator(ts.dirRoot)
+
+			err = allocator.ClaimPCI("0000:af:06.1", NewPCIOwner("cid", "net1", targetNetNS.Path()))
+			Expect(err).ToNot(HaveOccurred())
+
+			isAllocated, err := allocator.IsAllocated("0000:af:06.1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isAllocated).To(BeTrue())
+
+			Expect(allocator.DeleteAllocatedPCI("0000:af:06.1", NewPCIOwner("cid", "net1", ""))).To(Succeed())
+		})
+
+		It("Assuming is allocated and namespace exist", func() {
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			allocator := NewPCIAllocator(ts.dirRoot)
+
+			err = allocator.ClaimPCI("0000:af:06.1", NewPCIOwner("cid", "net1", targetNetNS.Path()))
+			Expect(err).ToNot(HaveOccurred())
+
+			err = allocator.ClaimPCI("0000:af:06.1", NewPCIOwner("cid", "net1", targetNetNS.Path()))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("pci address 0000:af:06.1 is already allocated"))
+
+			Expect(allocator.DeleteAllocatedPCI("0000:af:06.1", NewPCIOwner("cid", "net1", ""))).To(Succeed())
+		})
+
+		It("Assuming a stale claim from a namespace that doesn't exist", func() {
+			allocator := NewPCIAllocator(ts.dirRoot)
+			err = allocator.saveAllocatedPCI("0000:af:06.1", NewPCIOwner("old", "net1", "/var/run/netns/does-not-exist"))
+			Expect(err).ToNot(HaveOccurred())
+
+			targetNetNS, err = testutils.NewNS()
+			Expect(err).NotTo(HaveOccurred())
+			err = allocator.ClaimPCI("0000:af:06.1", NewPCIOwner("cid", "net1", targetNetNS.Path()))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(allocator.DeleteAllocatedPCI("0000:af:06.1", NewPCIOwner("cid", "net1", ""))).To(Succeed())
+		})
+	})
+
+	Context("IsOwner and FindAllocatedPCI", func() {
+		It("Assuming is allocated to the owner", func() {
+			allocator := NewPCIAllocator(ts.dirRoot)
+			err = allocator.saveAllocatedPCI("0000:af:06.1", NewPCIOwner("cid", "net1", "/proc/self/ns/net"))
+			Expect(err).ToNot(HaveOccurred())
+
+			isOwner, err := allocator.IsOwner("0000:af:06.1", NewPCIOwner("cid", "net1", ""))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isOwner).To(BeTrue())
+			Expect(allocator.FindAllocatedPCI(NewPCIOwner("cid", "net1", ""))).To(Equal("0000:af:06.1"))
+
+			Expect(allocator.DeleteAllocatedPCI("0000:af:06.1", NewPCIOwner("cid", "net1", ""))).To(Succeed())
+		})
+
+		It("Assuming is allocated to another attachment", func() {
+			allocator := NewPCIAllocator(ts.dirRoot)
+			err = allocator.saveAllocatedPCI("0000:af:06.1", NewPCIOwner("other", "net1", "/proc/self/ns/net"))
+			Expect(err).ToNot(HaveOccurred())
+
+			isOwner, err := allocator.IsOwner("0000:af:06.1", NewPCIOwner("cid", "net1", ""))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isOwner).To(BeFalse())
+			Expect(allocator.FindAllocatedPCI(NewPCIOwner("cid", "net1", ""))).To(BeEmpty())
+
+			Expect(allocator.DeleteAllocatedPCI("0000:af:06.1", NewPCIOwner("other", "net1", ""))).To(Succeed())
+		})
+
+		It("Assuming is not allocated", func() {
+			allocator := NewPCIAllocator(ts.dirRoot)
+			isOwner, err := allocator.IsOwner("0000:af:06.1", NewPCIOwner("cid", "net1", ""))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isOwner).To(BeFalse())
+		})
+
+		It("Assuming another allocation is truncated", func() {
+			allocator := NewPCIAllocator(ts.dirRoot)
+			err = allocator.saveAllocatedPCI("0000:af:06.1", NewPCIOwner("cid", "net1", "/proc/self/ns/net"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filepath.Join(ts.dirRoot, "pci", "0000:af:06.1.tmp")).NotTo(BeAnExistingFile())
+			truncated := filepath.Join(ts.dirRoot, "pci", "0000:af:06.0")
+			Expect(os.WriteFile(truncated, []byte(`{"containerID":"ot`), 0600)).To(Succeed())
+			defer os.Remove(truncated)
+
+			Expect(allocator.FindAllocatedPCI(NewPCIOwner("cid", "net1", ""))).To(Equal("0000:af:06.1"))
+
+			Expect(allocator.DeleteAllocatedPCI("0000:af:06.1", NewPCIOwner("cid", "net1", ""))).To(Succeed())
+		})
+	})
 })
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Mocked sysfs interface, this is required for unit tests
+
+// SriovSysfsManager is an interface to mock the VF attributes that some PF drivers, e.g. i40e,
+// expose under /sys/class/net/<pf>/device/sriov/<vf>
+type SriovSysfsManager interface {
+	VfTrunkGet(pfName string, vfID int) (string, error)
+	VfTrunkAdd(pfName string, vfID int, vlans string) error
+	VfTrunkRemove(pfName string, vfID int, vlans string) error
+	VfMirrorGet(pfName string, vfID int, direction string) (int, error)
+	VfMirrorAdd(pfName string, vfID int, direction string, target int) error
+	VfMirrorRemove(pfName string, vfID int, direction string, target int) error
+}
+
+const (
+	// MirrorIngress is the direction of the traffic received by a VF
+	MirrorIngress = "ingress"
+	// MirrorEgress is the direction of the traffic sent by a VF
+	MirrorEgress = "egress"
+	// MirrorOff is the mirror target of a VF whose traffic isn't mirrored
+	MirrorOff = -1
+)
+
+// MySysfs SriovSysfsManager
+type MySysfs struct {
+	SriovSysfsManager
+}
+
+// vfSysfsAttrPath returns the path of the driver specific sysfs attribute attr of the VF vfID of the PF pfName
+func vfSysfsAttrPath(pfName string, vfID int, attr string) string {
+	return filepath.Join(NetDirectory, pfName, "device", "sriov", strconv.Itoa(vfID), attr)
+}
+
+// readVfSysfsAttr reads a driver specific sysfs attribute of a VF
+// The returned error wraps os.ErrNotExist when the driver doesn't expose the attribute.
+func readVfSysfsAttr(pfName string, vfID int, attr string) (string, error) {
+	path := vfSysfsAttrPath(pfName, vfID, attr)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// writeVfSysfsAttr writes a driver specific sysfs attribute of a VF, which is never created if missing
+// The returned error wraps os.ErrNotExist when the driver doesn't expose the attribute.
+func writeVfSysfsAttr(pfName string, vfID int, attr, value string) error {
+	path := vfSysfsAttrPath(pfName, vfID, attr)
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(value); err != nil {
+		return fmt.Errorf("failed to write %q to %s: %w", value, path, err)
+	}
+	return nil
+}
+
+// VfTrunkGet returns the VLANs the VF accepts tagged, as a comma separated list of VLAN IDs and ranges
+func (s *MySysfs) VfTrunkGet(pfName string, vfID int) (string, error) {
+	return readVfSysfsAttr(pfName, vfID, "trunk")
+}
+
+// VfTrunkAdd adds the VLANs vlans, a comma separated list of VLAN IDs and ranges, to the VF trunk
+// Equivalent to: `echo add $vlans > /sys/class/net/$pf/device/sriov/$vf/trunk`
+func (s *MySysfs) VfTrunkAdd(pfName string, vfID int, vlans string) error {
+	return writeVfSysfsAttr(pfName, vfID, "trunk", "add "+vlans)
+}
+
+// VfTrunkRemove removes the VLANs vlans, a comma separated list of VLAN IDs and ranges, from the VF trunk
+// Equivalent to: `echo rem $vlans > /sys/class/net/$pf/device/sriov/$vf/trunk`
+func (s *MySysfs) VfTrunkRemove(pfName string, vfID int, vlans string) error {
+	return writeVfSysfsAttr(pfName, vfID, "trunk", "rem "+vlans)
+}
+
+// VfMirrorGet returns the VF the direction traffic of the VF is mirrored to, or MirrorOff
+func (s *MySysfs) VfMirrorGet(pfName string, vfID int, direction string) (int, error) {
+	value, err := readVfSysfsAttr(pfName, vfID, direction+"_mirror")
+	if err != nil {
+		return MirrorOff, err
+	}
+	if value == "off" || value == "" {
+		return MirrorOff, nil
+	}
+	target, err := strconv.Atoi(value)
+	if err != nil {
+		return MirrorOff, fmt.Errorf("invalid %s mirror %q of vf %d: %v", direction, value, vfID, err)
+	}
+	return target, nil
+}
+
+// VfMirrorAdd mirrors the direction traffic of the VF to the VF target
+// Equivalent to: `echo add $target > /sys/class/net/$pf/device/sriov/$vf/${direction}_mirror`
+func (s *MySysfs) VfMirrorAdd(pfName string, vfID int, direction string, target int) error {
+	return writeVfSysfsAttr(pfName, vfID, direction+"_mirror", "add "+strconv.Itoa(target))
+}
+
+// VfMirrorRemove stops mirroring the direction traffic of the VF to the VF target
+// Equivalent to: `echo rem $target > /sys/class/net/$pf/device/sriov/$vf/${direction}_mirror`
+func (s *MySysfs) VfMirrorRemove(pfName string, vfID int, direction string, target int) error {
+	return writeVfSysfsAttr(pfName, vfID, direction+"_mirror", "rem "+strconv.Itoa(target))
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sysfs", func() {
+	Context("Checking VF trunk functions", func() {
+		var trunkFile string
+		sysfs := &MySysfs{}
+
+		BeforeEach(func() {
+			trunkFile = filepath.Join(NetDirectory, "enp175s0f1", "device", "sriov", "0", "trunk")
+			Expect(os.MkdirAll(filepath.Dir(trunkFile), 0755)).To(Succeed())
+			Expect(os.WriteFile(trunkFile, []byte("100-200,300\n"), 0600)).To(Succeed())
+		})
+		AfterEach(func() {
+			Expect(os.RemoveAll(filepath.Join(NetDirectory, "enp175s0f1", "device", "sriov"))).To(Succeed())
+		})
+
+		It("Assuming existing trunk file", func() {
+			vlans, err := sysfs.VfTrunkGet("enp175s0f1", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vlans).To(Equal("100-200,300"))
+
+			Expect(sysfs.VfTrunkAdd("enp175s0f1", 0, "400")).To(Succeed())
+			data, err := os.ReadFile(trunkFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(HavePrefix("add 400"))
+
+			Expect(sysfs.VfTrunkRemove("enp175s0f1", 0, "100-200")).To(Succeed())
+			data, err = os.ReadFile(trunkFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(HavePrefix("rem 100-200"))
+		})
+		It("Assuming driver without trunk support", func() {
+			_, err := sysfs.VfTrunkGet("enp175s0f1", 1)
+			Expect(err).To(MatchError(os.ErrNotExist))
+
+			err = sysfs.VfTrunkAdd("enp175s0f1", 1, "100")
+			Expect(err).To(MatchError(os.ErrNotExist))
+			_, err = os.Stat(filepath.Join(NetDirectory, "enp175s0f1", "device", "sriov", "1", "trunk"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+	Context("Checking VF mirror functions", func() {
+		var sriovDir string
+		sysfs := &MySysfs{}
+
+		BeforeEach(func() {
+			sriovDir = filepath.Join(NetDirectory, "enp175s0f1", "device", "sriov", "0")
+			Expect(os.MkdirAll(sriovDir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sriovDir, "ingress_mirror"), []byte("off\n"), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sriovDir, "egress_mirror"), []byte("1\n"), 0600)).To(Succeed())
+		})
+		AfterEach(func() {
+			Expect(os.RemoveAll(filepath.Join(NetDirectory, "enp175s0f1", "device", "sriov"))).To(Succeed())
+		})
+
+		It("Assuming existing mirror files", func() {
+			target, err := sysfs.VfMirrorGet("enp175s0f1", 0, MirrorIngress)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal(MirrorOff))
+			target, err = sysfs.VfMirrorGet("enp175s0f1", 0, MirrorEgress)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal(1))
+
+			Expect(sysfs.VfMirrorAdd("enp175s0f1", 0, MirrorIngress, 1)).To(Succeed())
+			data, err := os.ReadFile(filepath.Join(sriovDir, "ingress_mirror"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(HavePrefix("add 1"))
+
+			Expect(sysfs.VfMirrorRemove("enp175s0f1", 0, MirrorEgress, 1)).To(Succeed())
+			data, err = os.ReadFile(filepath.Join(sriovDir, "egress_mirror"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("rem 1"))
+		})
+		It("Assuming driver without mirror support", func() {
+			_, err := sysfs.VfMirrorGet("enp175s0f1", 1, MirrorIngress)
+			Expect(err).To(MatchError(os.ErrNotExist))
+		})
+	})
+})
@@ -28,15 +28,40 @@ var ts = tmpSysFs{
 	dirList: []string{
 		"sys/class/net",
 		"sys/bus/pci/devices",
+		"sys/bus/auxiliary/devices",
+		"sys/bus/vdpa/devices",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0/net/enp175s6",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1/net/enp175s7",
 		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1",
 		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1d1",
+		"proc/sys/net/ipv4/conf/enp175s6",
+		"sys/devices/virtual/net/enp175s0f1_0",
+		"sys/devices/virtual/net/enp175s0f1_1",
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/mlx5_core.sf.4/net/enp175s0f1s4",
+		"sys/devices/virtual/net/enp175s0f1pf1sf4",
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0/net/ens2f0",
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.2/vdpa0/virtio0/net/eth0",
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.3/vdpa1/vhost-vdpa/vhost-vdpa-1",
 	},
 	fileList: map[string][]byte{
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/sriov_numvfs": []byte("2"),
 		"sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/sriov_numvfs": []byte("0"),
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0/sriov_numvfs": []byte("2"),
+		"proc/sys/net/ipv4/conf/enp175s6/rp_filter":                     []byte("1"),
+
+		// enp175s0f1 is in switchdev mode, with a representor for each of its VFs
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1/phys_switch_id": []byte("b8cef60300a1b2c3"),
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1/phys_port_name": []byte("p1"),
+		"sys/devices/virtual/net/enp175s0f1_0/phys_switch_id":                            []byte("b8cef60300a1b2c3"),
+		"sys/devices/virtual/net/enp175s0f1_0/phys_port_name":                            []byte("pf1vf0"),
+		"sys/devices/virtual/net/enp175s0f1_1/phys_switch_id":                            []byte("b8cef60300a1b2c3"),
+		"sys/devices/virtual/net/enp175s0f1_1/phys_port_name":                            []byte("pf1vf1"),
+
+		// enp175s0f1 has the Scalable Function 4, with its representor
+		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/mlx5_core.sf.4/sfnum": []byte("4"),
+		"sys/devices/virtual/net/enp175s0f1pf1sf4/phys_switch_id":               []byte("b8cef60300a1b2c3"),
+		"sys/devices/virtual/net/enp175s0f1pf1sf4/phys_port_name":               []byte("pf1sf4"),
 	},
 	netSymlinks: map[string]string{
 		"sys/class/net/enp175s0f1": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/net/enp175s0f1",
@@ -44,6 +69,16 @@ var ts = tmpSysFs{
 		"sys/class/net/enp175s7":   "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1/net/enp175s7",
 		"sys/class/net/ens1":       "sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1",
 		"sys/class/net/ens1d1":     "sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0/net/ens1d1",
+
+		"sys/class/net/enp175s0f1_0": "sys/devices/virtual/net/enp175s0f1_0",
+		"sys/class/net/enp175s0f1_1": "sys/devices/virtual/net/enp175s0f1_1",
+
+		"sys/class/net/enp175s0f1s4":     "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/mlx5_core.sf.4/net/enp175s0f1s4",
+		"sys/class/net/enp175s0f1pf1sf4": "sys/devices/virtual/net/enp175s0f1pf1sf4",
+
+		// the VFs of ens2f0 are used through vDPA devices, bound to virtio_vdpa and vhost_vdpa
+		"sys/class/net/ens2f0": "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0/net/ens2f0",
+		"sys/class/net/eth0":   "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.2/vdpa0/virtio0/net/eth0",
 	},
 	devSymlinks: map[string]string{
 		"sys/class/net/enp175s0f1/device": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1",
@@ -56,6 +91,19 @@ var ts = tmpSysFs{
 		"sys/bus/pci/devices/0000:af:06.0": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0",
 		"sys/bus/pci/devices/0000:af:06.1": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1",
 		"sys/bus/pci/devices/0000:05:00.0": "sys/devices/pci0000:00/0000:00:02.0/0000:05:00.0",
+
+		"sys/class/net/enp175s0f1s4/device":        "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/mlx5_core.sf.4",
+		"sys/bus/auxiliary/devices/mlx5_core.sf.4": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/mlx5_core.sf.4",
+
+		"sys/class/net/ens2f0/device":      "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0",
+		"sys/bus/pci/devices/0000:3b:00.0": "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0",
+		"sys/bus/pci/devices/0000:3b:00.2": "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.2",
+		"sys/bus/pci/devices/0000:3b:00.3": "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.3",
+		"sys/bus/vdpa/devices/vdpa0":       "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.2/vdpa0",
+		"sys/bus/vdpa/devices/vdpa1":       "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.3/vdpa1",
+
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.2/vdpa0/driver": "sys/bus/vdpa/drivers/virtio_vdpa",
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.3/vdpa1/driver": "sys/bus/vdpa/drivers/vhost_vdpa",
 	},
 	vfSymlinks: map[string]string{
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/virtfn0": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.0",
@@ -63,6 +111,11 @@ var ts = tmpSysFs{
 
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1/virtfn1": "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1",
 		"sys/devices/pci0000:ae/0000:ae:00.0/0000:af:06.1/physfn":  "sys/devices/pci0000:ae/0000:ae:00.0/0000:af:00.1",
+
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0/virtfn0": "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.2",
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.2/physfn":  "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0",
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0/virtfn1": "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.3",
+		"sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.3/physfn":  "sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0",
 	},
 }
 
@@ -110,7 +163,10 @@ func CreateTmpSysFs() error {
 	}
 
 	SysBusPci = filepath.Join(ts.dirRoot, SysBusPci)
+	SysBusAuxiliary = filepath.Join(ts.dirRoot, SysBusAuxiliary)
+	SysBusVdpa = filepath.Join(ts.dirRoot, SysBusVdpa)
 	NetDirectory = filepath.Join(ts.dirRoot, NetDirectory)
+	SysctlDirectory = filepath.Join(ts.dirRoot, SysctlDirectory)
 	return nil
 }
 
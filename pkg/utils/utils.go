@@ -7,9 +7,14 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/safchain/ethtool"
+
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 )
 
 var (
@@ -18,10 +23,16 @@ var (
 	NetDirectory = "/sys/class/net"
 	// SysBusPci is sysfs pci device directory
 	SysBusPci = "/sys/bus/pci/devices"
+	// SysBusAuxiliary is sysfs auxiliary device directory, where Scalable Functions are found
+	SysBusAuxiliary = "/sys/bus/auxiliary/devices"
+	// SysBusVdpa is sysfs vdpa device directory
+	SysBusVdpa = "/sys/bus/vdpa/devices"
 	// SysV4ArpNotify is the sysfs IPv4 ARP Notify directory
 	SysV4ArpNotify = "/proc/sys/net/ipv4/conf/"
 	// SysV6NdiscNotify is the sysfs IPv6 Neighbor Discovery Notify directory
 	SysV6NdiscNotify = "/proc/sys/net/ipv6/conf/"
+	// SysctlDirectory is the procfs sysctl directory
+	SysctlDirectory = "/proc/sys"
 	// UserspaceDrivers is a list of driver names that don't have netlink representation for their devices
 	UserspaceDrivers = []string{"vfio-pci", "uio_pci_generic", "igb_uio"}
 )
@@ -49,6 +60,38 @@ func EnableArpAndNdiscNotify(ifName string) error {
 	return nil
 }
 
+// IfSysctlPlaceholder is the interface name of per-interface sysctl keys standing for the pod interface
+const IfSysctlPlaceholder = "IFNAME"
+
+// ParseIfSysctl splits a per-interface sysctl key, net.ipv4.conf.<if>.<param> or net.ipv6.conf.<if>.<param>,
+// into its IP version, interface name and parameter
+func ParseIfSysctl(key string) (ipVersion, ifName, param string, err error) {
+	parts := strings.Split(key, ".")
+	if len(parts) != 5 || parts[0] != "net" || (parts[1] != "ipv4" && parts[1] != "ipv6") || parts[2] != "conf" {
+		return "", "", "", fmt.Errorf("sysctl %q is not under net.ipv4.conf.<if> or net.ipv6.conf.<if>", key)
+	}
+	if parts[3] == "" || parts[4] == "" {
+		return "", "", "", fmt.Errorf("sysctl %q is missing its interface or parameter name", key)
+	}
+	return parts[1], parts[3], parts[4], nil
+}
+
+// SetIfSysctl writes value to the per-interface sysctl param of ifName, for the IP version ipVersion
+// Equivalent to: `sysctl -w net.$ipVersion.conf.$ifName.$param=$value`
+func SetIfSysctl(ipVersion, ifName, param, value string) error {
+	path := filepath.Join(SysctlDirectory, "net", ipVersion, "conf", ifName, param)
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open sysctl net.%s.conf.%s.%s: %w", ipVersion, ifName, param, err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(value); err != nil {
+		return fmt.Errorf("failed to write %q to sysctl net.%s.conf.%s.%s: %w", value, ipVersion, ifName, param, err)
+	}
+	return nil
+}
+
 // GetSriovNumVfs takes in a PF name(ifName) as string and returns number of VF configured as int
 func GetSriovNumVfs(ifName string) (int, error) {
 	var vfTotal int
@@ -76,6 +119,26 @@ func GetSriovNumVfs(ifName string) (int, error) {
 	return vfTotal, nil
 }
 
+// GetSriovPFs returns the names of the PF net devices that have at least one VF configured
+func GetSriovPFs() ([]string, error) {
+	netDevs, err := os.ReadDir(NetDirectory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read net devices in %s: %v", NetDirectory, err)
+	}
+
+	var pfs []string
+	for _, netDev := range netDevs {
+		// Devices that are not SR-IOV capable have no sriov_numvfs file; skip them
+		numVfs, err := GetSriovNumVfs(netDev.Name())
+		if err != nil || numVfs == 0 {
+			continue
+		}
+		pfs = append(pfs, netDev.Name())
+	}
+
+	return pfs, nil
+}
+
 // GetVfid takes in VF's PCI address(addr) and pfName as string and returns VF's ID as int
 func GetVfid(addr string, pfName string) (int, error) {
 	var id int
@@ -216,6 +279,193 @@ func GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error) {
 	return names, nil
 }
 
+// GetLinkPciAddress returns the pci address of the device behind the netdev ifName in the current netns
+// It relies on the bus info reported by the driver, since the sysfs net class of another netns is not visible.
+func GetLinkPciAddress(ifName string) (string, error) {
+	busInfo, err := ethtool.BusInfo(ifName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get bus info of %s: %v", ifName, err)
+	}
+	return busInfo, nil
+}
+
+// GetDriverName returns the name of the driver bound to the device of the netdev ifName
+func GetDriverName(ifName string) (string, error) {
+	driverPath, err := filepath.EvalSymlinks(filepath.Join(NetDirectory, ifName, "device", "driver"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read driver of %s: %v", ifName, err)
+	}
+	return filepath.Base(driverPath), nil
+}
+
+// GetPfPciAddress returns the pci address of the PF pfName
+func GetPfPciAddress(pfName string) (string, error) {
+	devicePath, err := filepath.EvalSymlinks(filepath.Join(NetDirectory, pfName, "device"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read device of %s: %v", pfName, err)
+	}
+	return filepath.Base(devicePath), nil
+}
+
+// representorPortName matches the phys_port_name of a VF or SF representor,
+// [c<controller>]pf<pf number>vf<vf id> or [c<controller>]pf<pf number>sf<sf number>
+var representorPortName = regexp.MustCompile(`^(?:c\d+)?pf(\d+)(vf|sf)(\d+)$`)
+
+// readNetAttr reads the sysfs attribute attr of the netdev ifName
+func readNetAttr(ifName, attr string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(NetDirectory, ifName, attr))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// GetVfRepresentor returns the representor netdev of the VF vfID of the PF pfName, whose eswitch is in switchdev mode.
+// The representor shares the phys_switch_id of the PF uplink, and its phys_port_name is pf<N>vf<vfID>, N being the
+// number in the p<N> phys_port_name of the uplink.
+func GetVfRepresentor(pfName string, vfID int) (string, error) {
+	return getRepresentor(pfName, "vf", vfID)
+}
+
+// GetSfRepresentor returns the representor netdev of the Scalable Function sfNum of the PF pfName, whose
+// phys_port_name is pf<N>sf<sfNum>, N being the number in the p<N> phys_port_name of the uplink.
+func GetSfRepresentor(pfName string, sfNum int) (string, error) {
+	return getRepresentor(pfName, "sf", sfNum)
+}
+
+// getRepresentor returns the representor netdev of the function id of kind, vf or sf, of the PF pfName
+func getRepresentor(pfName, kind string, id int) (string, error) {
+	switchID, err := readNetAttr(pfName, "phys_switch_id")
+	if err != nil || switchID == "" {
+		return "", fmt.Errorf("failed to read switch id of %s: %v", pfName, err)
+	}
+	uplinkPortName, err := readNetAttr(pfName, "phys_port_name")
+	if err != nil || !strings.HasPrefix(uplinkPortName, "p") {
+		return "", fmt.Errorf("failed to read uplink port name of %s: %v", pfName, err)
+	}
+	pfNum, err := strconv.Atoi(strings.TrimPrefix(uplinkPortName, "p"))
+	if err != nil {
+		return "", fmt.Errorf("invalid uplink port name %q of %s", uplinkPortName, pfName)
+	}
+
+	netDevs, err := os.ReadDir(NetDirectory)
+	if err != nil {
+		return "", fmt.Errorf("failed to read net devices in %s: %v", NetDirectory, err)
+	}
+	for _, netDev := range netDevs {
+		// Netdevs that are not switch ports have no phys_switch_id nor phys_port_name; skip them
+		if id, err := readNetAttr(netDev.Name(), "phys_switch_id"); err != nil || id != switchID {
+			continue
+		}
+		portName, err := readNetAttr(netDev.Name(), "phys_port_name")
+		if err != nil {
+			continue
+		}
+		match := representorPortName.FindStringSubmatch(portName)
+		if match != nil && match[1] == strconv.Itoa(pfNum) && match[2] == kind && match[3] == strconv.Itoa(id) {
+			return netDev.Name(), nil
+		}
+	}
+
+	return "", fmt.Errorf("representor of %s %d of %s not found", kind, id, pfName)
+}
+
+// auxDeviceName matches the name of an auxiliary bus device, <module>.<device>.<id>, e.g. mlx5_core.sf.4
+var auxDeviceName = regexp.MustCompile(`^[\w-]+\.[\w-]+\.\d+$`)
+
+// IsAuxDevice tells whether deviceID is the name of an auxiliary bus device rather than a pci address
+func IsAuxDevice(deviceID string) bool {
+	return auxDeviceName.MatchString(deviceID)
+}
+
+// GetSfInfo returns the PF netdev and the SF number of the Scalable Function auxiliary device auxDev
+func GetSfInfo(auxDev string) (string, int, error) {
+	devicePath, err := filepath.EvalSymlinks(filepath.Join(SysBusAuxiliary, auxDev))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read auxiliary device %s: %v", auxDev, err)
+	}
+
+	// Only Scalable Functions have an sfnum attribute
+	data, err := os.ReadFile(filepath.Join(devicePath, "sfnum"))
+	if err != nil {
+		return "", 0, fmt.Errorf("auxiliary device %s is not a scalable function: %v", auxDev, err)
+	}
+	sfNum, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid sfnum %q of %s", strings.TrimSpace(string(data)), auxDev)
+	}
+
+	// The SF is a child of the pci device of its PF
+	files, err := os.ReadDir(filepath.Join(filepath.Dir(devicePath), "net"))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read PF net devices of %s: %v", auxDev, err)
+	}
+	if len(files) < 1 {
+		return "", 0, fmt.Errorf("PF network device of %s not found", auxDev)
+	}
+
+	return files[0].Name(), sfNum, nil
+}
+
+// GetSfLinkName returns the netdev of the Scalable Function auxiliary device auxDev
+func GetSfLinkName(auxDev string) (string, error) {
+	sfDir := filepath.Join(SysBusAuxiliary, auxDev, "net")
+	fInfos, err := os.ReadDir(sfDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read net dir of the device %s: %v", auxDev, err)
+	}
+	if len(fInfos) == 0 {
+		return "", fmt.Errorf("SF device %s sysfs path (%s) has no entries", auxDev, sfDir)
+	}
+	return fInfos[0].Name(), nil
+}
+
+// GetVdpaDevice returns the vDPA device created on the VF pciAddr, or nil if it has none
+func GetVdpaDevice(pciAddr string) (*sriovtypes.VdpaDevice, error) {
+	devices, err := os.ReadDir(SysBusVdpa)
+	if os.IsNotExist(err) {
+		// the vdpa bus is not loaded
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vdpa devices in %s: %v", SysBusVdpa, err)
+	}
+
+	for _, device := range devices {
+		// vDPA devices are children of the pci device they are created on
+		devicePath, err := filepath.EvalSymlinks(filepath.Join(SysBusVdpa, device.Name()))
+		if err != nil || filepath.Base(filepath.Dir(devicePath)) != pciAddr {
+			continue
+		}
+
+		vdpaDev := &sriovtypes.VdpaDevice{Name: device.Name()}
+		if driverPath, err := filepath.EvalSymlinks(filepath.Join(devicePath, "driver")); err == nil {
+			vdpaDev.Driver = filepath.Base(driverPath)
+		}
+
+		// vhost_vdpa creates a vhost-vdpa-N character device, virtio_vdpa a virtioN device with its netdev
+		if vhostDevs, err := os.ReadDir(filepath.Join(devicePath, "vhost-vdpa")); err == nil && len(vhostDevs) > 0 {
+			vdpaDev.Path = filepath.Join("/dev", vhostDevs[0].Name())
+		}
+		entries, err := os.ReadDir(devicePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vdpa device %s: %v", device.Name(), err)
+		}
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Name(), "virtio") {
+				continue
+			}
+			if netDevs, err := os.ReadDir(filepath.Join(devicePath, entry.Name(), "net")); err == nil && len(netDevs) > 0 {
+				vdpaDev.NetDev = netDevs[0].Name()
+			}
+		}
+
+		return vdpaDev, nil
+	}
+
+	return nil, nil
+}
+
 // HasDpdkDriver checks if a device is attached to dpdk supported driver
 func HasDpdkDriver(pciAddr string) (bool, error) {
 	driverLink := filepath.Join(SysBusPci, pciAddr, "driver")
@@ -258,19 +508,65 @@ func saveScratchNetConf(containerID, dataDir string, netconf []byte) error {
 
 	path := filepath.Join(dataDir, containerID)
 
-	err := os.WriteFile(path, netconf, 0600)
+	// The data is written to a temporary file, synced and renamed into place so that a crash never leaves
+	// a truncated cache file behind
+	tmp, err := os.CreateTemp(dataDir, "."+containerID+"-*.tmp")
 	if err != nil {
+		return fmt.Errorf("failed to create temporary file for container data in the path(%q): %v", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(netconf); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write container data in the path(%q): %v", tmp.Name(), err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
 		return fmt.Errorf("failed to write container data in the path(%q): %v", path, err)
 	}
 
-	return err
+	return syncDir(dataDir)
+}
+
+// syncDir flushes the entries of dir to disk, making a rename in it durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory %s: %v", dir, err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync directory %s: %v", dir, err)
+	}
+	return nil
+}
+
+// CheckDirWritable verifies that files can be created in dataDir, creating the directory if needed
+func CheckDirWritable(dataDir string) error {
+	if err := os.MkdirAll(dataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create the sriov data directory(%q): %v", dataDir, err)
+	}
+
+	f, err := os.CreateTemp(dataDir, ".status-")
+	if err != nil {
+		return fmt.Errorf("sriov data directory(%q) is not writable: %v", dataDir, err)
+	}
+	f.Close()
+
+	return os.Remove(f.Name())
 }
 
 // ReadScratchNetConf takes in container ID, Pod interface name and data dir as string and returns a pointer to Conf
 func ReadScratchNetConf(cRefPath string) ([]byte, error) {
 	data, err := os.ReadFile(cRefPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read container data in the path(%q): %v", cRefPath, err)
+		return nil, fmt.Errorf("failed to read container data in the path(%q): %w", cRefPath, err)
 	}
 
 	return data, err
@@ -284,6 +580,33 @@ func CleanCachedNetConf(cRefPath string) error {
 	return nil
 }
 
+// SaveDeviceInfo writes the device-info of an attachment to path, where Multus reads it
+func SaveDeviceInfo(path string, devInfo *sriovtypes.DeviceInfo) error {
+	data, err := json.Marshal(devInfo)
+	if err != nil {
+		return fmt.Errorf("error serializing device-info: %v", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create the device-info directory of %s: %v", path, err)
+	}
+	// device-info files are read-only, replace a leftover one rather than writing into it
+	if err = CleanDeviceInfo(path); err != nil {
+		return err
+	}
+	if err = os.WriteFile(path, data, 0444); err != nil {
+		return fmt.Errorf("failed to write device-info file %s: %v", path, err)
+	}
+	return nil
+}
+
+// CleanDeviceInfo removes the device-info file path, if it exists
+func CleanDeviceInfo(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing device-info file %s: %v", path, err)
+	}
+	return nil
+}
+
 // SetVFEffectiveMAC will try to set the mac address on a specific VF interface
 //
 // the function will also validate that the mac address was configured as expect
@@ -3,6 +3,8 @@ package utils
 import (
 	"errors"
 	"net"
+	"os"
+	"path/filepath"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -10,6 +12,7 @@ import (
 
 	"github.com/vishvananda/netlink"
 
+	sriovtypes "github.com/k8snetworkplumbingwg/sriov-cni/pkg/types"
 	mocks_utils "github.com/k8snetworkplumbingwg/sriov-cni/pkg/utils/mocks"
 )
 
@@ -26,6 +29,13 @@ var _ = Describe("Utils", func() {
 			Expect(err).To(HaveOccurred(), "Not existing sriov interface should return an error")
 		})
 	})
+	Context("Checking GetSriovPFs function", func() {
+		It("Returns only PFs with VFs configured", func() {
+			result, err := GetSriovPFs()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal([]string{"enp175s0f1", "ens2f0"}))
+		})
+	})
 	Context("Checking GetVfid function", func() {
 		It("Assuming existing interface", func() {
 			result, err := GetVfid("0000:af:06.0", "enp175s0f1")
@@ -63,6 +73,82 @@ var _ = Describe("Utils", func() {
 			Expect(err).To(HaveOccurred(), "Not existing VF id should return an error")
 		})
 	})
+	Context("Checking GetPfPciAddress function", func() {
+		It("Assuming existing PF", func() {
+			Expect(GetPfPciAddress("enp175s0f1")).To(Equal("0000:af:00.1"))
+		})
+		It("Assuming not existing PF", func() {
+			_, err := GetPfPciAddress("enp175s0f2")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetVfRepresentor function", func() {
+		It("Assuming existing representors", func() {
+			Expect(GetVfRepresentor("enp175s0f1", 0)).To(Equal("enp175s0f1_0"))
+			Expect(GetVfRepresentor("enp175s0f1", 1)).To(Equal("enp175s0f1_1"))
+		})
+		It("Assuming not existing representor", func() {
+			_, err := GetVfRepresentor("enp175s0f1", 2)
+			Expect(err).To(HaveOccurred())
+		})
+		It("Assuming PF not in switchdev mode", func() {
+			_, err := GetVfRepresentor("ens1", 0)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to read switch id of ens1"))
+		})
+	})
+	Context("Checking Scalable Function functions", func() {
+		It("Assuming an SF auxiliary device name", func() {
+			Expect(IsAuxDevice("mlx5_core.sf.4")).To(BeTrue())
+			Expect(IsAuxDevice("0000:af:06.0")).To(BeFalse())
+		})
+		It("Assuming existing SF", func() {
+			pfName, sfNum, err := GetSfInfo("mlx5_core.sf.4")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pfName).To(Equal("enp175s0f1"))
+			Expect(sfNum).To(Equal(4))
+			Expect(GetSfLinkName("mlx5_core.sf.4")).To(Equal("enp175s0f1s4"))
+			Expect(GetSfRepresentor("enp175s0f1", 4)).To(Equal("enp175s0f1pf1sf4"))
+		})
+		It("Assuming not existing SF", func() {
+			_, _, err := GetSfInfo("mlx5_core.sf.5")
+			Expect(err).To(HaveOccurred())
+			_, err = GetSfRepresentor("enp175s0f1", 0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking GetVdpaDevice function", func() {
+		It("Assuming VF bound to virtio_vdpa", func() {
+			vdpaDev, err := GetVdpaDevice("0000:3b:00.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vdpaDev).To(Equal(&sriovtypes.VdpaDevice{Name: "vdpa0", Driver: "virtio_vdpa", NetDev: "eth0"}))
+		})
+		It("Assuming VF bound to vhost_vdpa", func() {
+			vdpaDev, err := GetVdpaDevice("0000:3b:00.3")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vdpaDev).To(Equal(&sriovtypes.VdpaDevice{Name: "vdpa1", Driver: "vhost_vdpa", Path: "/dev/vhost-vdpa-1"}))
+		})
+		It("Assuming VF without vDPA device", func() {
+			vdpaDev, err := GetVdpaDevice("0000:af:06.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vdpaDev).To(BeNil())
+		})
+	})
+	Context("Checking SaveDeviceInfo function", func() {
+		It("Writes and removes the device-info file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "device-info", "net1.json")
+			devInfo := &sriovtypes.DeviceInfo{Type: "vdpa", Version: "1.1.0"}
+			Expect(SaveDeviceInfo(path, devInfo)).To(Succeed())
+			// a leftover file is replaced
+			Expect(SaveDeviceInfo(path, devInfo)).To(Succeed())
+			data, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"type":"vdpa","version":"1.1.0"}`))
+			Expect(CleanDeviceInfo(path)).To(Succeed())
+			Expect(path).NotTo(BeAnExistingFile())
+			Expect(CleanDeviceInfo(path)).To(Succeed())
+		})
+	})
 	Context("Checking GetSharedPF function", func() {
 		/* TO-DO */
 		// It("Assuming existing interface", func() {
@@ -96,6 +182,42 @@ var _ = Describe("Utils", func() {
 			Expect(err).NotTo((HaveOccurred()), "Retry should not return an error")
 		})
 	})
+	Context("Checking ParseIfSysctl function", func() {
+		It("Assuming ipv4 interface sysctl", func() {
+			ipVersion, ifName, param, err := ParseIfSysctl("net.ipv4.conf.IFNAME.rp_filter")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipVersion).To(Equal("ipv4"))
+			Expect(ifName).To(Equal(IfSysctlPlaceholder))
+			Expect(param).To(Equal("rp_filter"))
+		})
+		It("Assuming ipv6 interface sysctl", func() {
+			ipVersion, ifName, param, err := ParseIfSysctl("net.ipv6.conf.net1.accept_ra")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ipVersion).To(Equal("ipv6"))
+			Expect(ifName).To(Equal("net1"))
+			Expect(param).To(Equal("accept_ra"))
+		})
+		It("Assuming sysctl not under an interface", func() {
+			for _, key := range []string{"net.ipv4.ip_forward", "net.core.somaxconn", "net.ipv4.conf..rp_filter", "kernel.pid_max"} {
+				_, _, _, err := ParseIfSysctl(key)
+				Expect(err).To(HaveOccurred(), key)
+			}
+		})
+	})
+	Context("Checking SetIfSysctl function", func() {
+		It("Assuming existing sysctl", func() {
+			err := SetIfSysctl("ipv4", "enp175s6", "rp_filter", "2")
+			Expect(err).NotTo(HaveOccurred())
+			data, err := os.ReadFile(filepath.Join(SysctlDirectory, "net/ipv4/conf/enp175s6/rp_filter"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("2"))
+		})
+		It("Assuming not existing sysctl", func() {
+			err := SetIfSysctl("ipv4", "enp175s6", "no_such_param", "1")
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+		})
+	})
 	Context("Checking SetVFEffectiveMAC function", func() {
 		It("assuming calling function fails", func() {
 			mocked := &mocks_utils.NetlinkManager{}